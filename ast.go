@@ -0,0 +1,150 @@
+package gherkin
+
+// Position of a node within a feature file. Lines and columns both
+// start at 1.
+type Location struct {
+    Line int
+    Column int
+}
+
+// Returns the location itself, so that every node embedding a Location
+// satisfies the Node interface.
+func (l Location) Pos() Location {
+    return l
+}
+
+// Implemented by every node of the Gherkin AST.
+type Node interface {
+    Pos() Location
+}
+
+// A tag such as @wip. Name includes the leading '@'.
+type Tag struct {
+    Location
+    Name string
+}
+
+// A '#' comment line. Text includes the leading '#'.
+type Comment struct {
+    Location
+    Text string
+}
+
+// A multi-line string argument to a step, delimited by """ or ```.
+// MediaType is the optional content type following the opening
+// delimiter, as in """json. Content is dedented relative to the opening
+// delimiter.
+type DocString struct {
+    Location
+    Delimiter string
+    MediaType string
+    Content string
+}
+
+type TableCell struct {
+    Location
+    Value string
+}
+
+type TableRow struct {
+    Location
+    Cells []*TableCell
+}
+
+// Returns the values of all cells in the row.
+func (tr *TableRow) Values() []string {
+    values := make([]string, len(tr.Cells))
+    for i, c := range tr.Cells {
+        values[i] = c.Value
+    }
+    return values
+}
+
+// A table argument to a step. The first row holds the column names.
+type DataTable struct {
+    Location
+    Rows []*TableRow
+}
+
+// A single Given/When/Then/And/But/* line. Keyword does not include
+// the separating whitespace.
+type Step struct {
+    Location
+    Keyword string
+    Text string
+    DataTable *DataTable
+    DocString *DocString
+}
+
+type Background struct {
+    Location
+    Keyword string
+    Name string
+    Description string
+    Steps []*Step
+}
+
+// Implemented by *Scenario and *ScenarioOutline.
+type ScenarioDefinition interface {
+    Node
+    scenarioDefinition()
+}
+
+type Scenario struct {
+    Location
+    Tags []*Tag
+    Keyword string
+    Name string
+    Description string
+    Steps []*Step
+}
+
+func (s *Scenario) scenarioDefinition() {}
+
+type ScenarioOutline struct {
+    Location
+    Tags []*Tag
+    Keyword string
+    Name string
+    Description string
+    Steps []*Step
+    Examples []*Examples
+}
+
+func (so *ScenarioOutline) scenarioDefinition() {}
+
+// An Examples block of a scenario outline. TableHeader names the
+// placeholders and each row of TableBody yields one scenario.
+type Examples struct {
+    Location
+    Tags []*Tag
+    Keyword string
+    Name string
+    Description string
+    TableHeader *TableRow
+    TableBody []*TableRow
+}
+
+type Rule struct {
+    Location
+    Tags []*Tag
+    Keyword string
+    Name string
+    Description string
+    Background *Background
+    Scenarios []ScenarioDefinition
+}
+
+// The root of the AST. Scenarios declared outside any Rule always
+// precede the rules, so Scenarios followed by Rules is document order.
+type Feature struct {
+    Location
+    Tags []*Tag
+    Keyword string
+    Name string
+    Description string
+    Background *Background
+    Scenarios []ScenarioDefinition
+    Rules []*Rule
+    Comments []*Comment
+}
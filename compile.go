@@ -0,0 +1,164 @@
+package gherkin
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Converts a parsed feature into the scenarios executed by the Runner.
+// Scenario outlines are expanded into one scenario per example row, and
+// every scenario carries the tags it inherits from its feature, rule and
+// examples.
+func compileFeature(f *Feature) []runnable {
+    backgrounds := []*scenario{}
+    if f.Background != nil {
+        backgrounds = append(backgrounds, compileBackground(f.Background))
+    }
+    featureTags := tagNames(nil, f.Tags)
+    runnables := compileScenarios(f.Scenarios, backgrounds, featureTags)
+    for _, rule := range f.Rules {
+        ruleBackgrounds := backgrounds
+        if rule.Background != nil {
+            ruleBackgrounds = append(backgrounds[:len(backgrounds):len(backgrounds)], compileBackground(rule.Background))
+        }
+        ruleTags := tagNames(featureTags, rule.Tags)
+        for _, rn := range compileScenarios(rule.Scenarios, ruleBackgrounds, ruleTags) {
+            rn.(*scenario).rule = rule
+            runnables = append(runnables, rn)
+        }
+    }
+    for _, rn := range runnables {
+        rn.(*scenario).feature = f.Name
+    }
+    return runnables
+}
+
+func compileBackground(bg *Background) *scenario {
+    return &scenario{
+        orig: renderHeader(bg.Location, bg.Keyword, bg.Name),
+        isBackground: true,
+        background: bg,
+        steps: compileSteps(bg.Steps)}
+}
+
+func compileScenarios(definitions []ScenarioDefinition, backgrounds []*scenario, tags []string) []runnable {
+    runnables := []runnable{}
+    for _, definition := range definitions {
+        switch def := definition.(type) {
+        case *Scenario:
+            runnables = append(runnables, &scenario{
+                orig: renderHeader(def.Location, def.Keyword, def.Name),
+                name: def.Name,
+                location: def.Location,
+                definition: def,
+                steps: compileSteps(def.Steps),
+                backgrounds: backgrounds,
+                tags: tagNames(tags, def.Tags)})
+        case *ScenarioOutline:
+            runnables = append(runnables, compileScenarioOutline(def, backgrounds, tags)...)
+        }
+    }
+    return runnables
+}
+
+func compileScenarioOutline(def *ScenarioOutline, backgrounds []*scenario, tags []string) []runnable {
+    runnables := []runnable{}
+    so := createScenarioOutline()
+    for _, s := range compileSteps(def.Steps) {
+        so.AddStep(s)
+    }
+    outlineTags := tagNames(tags, def.Tags)
+    rowCount := 0
+    for _, examples := range def.Examples {
+        exampleTags := tagNames(outlineTags, examples.Tags)
+        if examples.TableHeader == nil {
+            continue
+        }
+        keys := examples.TableHeader.Values()
+        for _, row := range examples.TableBody {
+            s := so.CreateForExample(createTableMap(keys, row.Values()))
+            s.orig = renderTableRow(row)
+            s.location = row.Location
+            rowCount++
+            s.name = strings.TrimSpace(fmt.Sprintf("%s #%d", def.Name, rowCount))
+            s.definition = def
+            s.examples = examples
+            s.row = row
+            s.backgrounds = backgrounds
+            s.tags = exampleTags
+            runnables = append(runnables, &s)
+        }
+    }
+    return runnables
+}
+
+func compileSteps(steps []*Step) []step {
+    compiled := []step{}
+    keyword := "Given"
+    for _, s := range steps {
+        switch s.Keyword {
+        case "Given", "When", "Then":
+            keyword = s.Keyword
+        }
+        st := StepFromStringAndOrig(s.Text, renderStep(s))
+        st.docString = s.DocString
+        st.source = s
+        st.keyword = keyword
+        if s.DataTable != nil {
+            for _, row := range s.DataTable.Rows {
+                st.rows = append(st.rows, row.Values())
+            }
+            st.setMlKeys(s.DataTable.Rows[0].Values())
+            for _, row := range s.DataTable.Rows[1:] {
+                st.addMlData(createTableMap(st.keys, row.Values()))
+            }
+        }
+        compiled = append(compiled, st)
+    }
+    return compiled
+}
+
+// Appends the names of tags to inherited, skipping duplicates.
+func tagNames(inherited []string, tags []*Tag) []string {
+    names := append([]string{}, inherited...)
+    for _, tag := range tags {
+        found := false
+        for _, name := range names {
+            found = found || name == tag.Name
+        }
+        if !found {
+            names = append(names, tag.Name)
+        }
+    }
+    return names
+}
+
+func createTableMap(keys []string, fields []string) (l map[string]string) {
+    l = map[string]string{}
+    for i, k := range keys {
+        l[k] = fields[i]
+    }
+    return
+}
+
+func indent(loc Location) string {
+    if loc.Column > 1 {
+        return strings.Repeat(" ", loc.Column - 1)
+    }
+    return ""
+}
+
+func renderHeader(loc Location, keyword, name string) string {
+    if name == "" {
+        return indent(loc) + keyword + ":"
+    }
+    return indent(loc) + keyword + ": " + name
+}
+
+func renderStep(s *Step) string {
+    return indent(s.Location) + s.Keyword + " " + s.Text
+}
+
+func renderTableRow(row *TableRow) string {
+    return indent(row.Location) + "| " + strings.Join(row.Values(), " | ") + " |"
+}
@@ -0,0 +1,188 @@
+package gherkin
+
+import (
+    "fmt"
+    re "regexp"
+    "strings"
+    "unicode"
+)
+
+var (
+    expressionParameter = re.MustCompile(`\{([^{}]*)\}`)
+    // Optional text attached to a word, as in cuke(s), and alternation
+    // between words, as in belly/stomach.
+    expressionOptional = re.MustCompile(`[\pL\pN_]\([\pL\pN_ ]+\)`)
+    expressionAlternation = re.MustCompile(`[\pL\pN_]/[\pL\pN_]`)
+    // Regex syntax that has no meaning in a Cucumber Expression.
+    regexOnlySyntax = re.MustCompile(`[.*+?|\[\]\\]`)
+)
+
+// Whether pattern should be read as a Cucumber Expression rather than a
+// regular expression: it is not anchored with ^ or $, and uses at least
+// one {parameter} of a known type, or optional text or alternation
+// without any other regex syntax. Other unanchored patterns stay regular
+// expressions, so that plain text such as "." keeps its regex meaning.
+func isCucumberExpression(pattern string, types *parameterTypes) bool {
+    if strings.HasPrefix(pattern, "^") || strings.HasSuffix(pattern, "$") {
+        return false
+    }
+    for _, m := range expressionParameter.FindAllStringSubmatch(pattern, -1) {
+        if _, ok := types.byName[m[1]]; ok {
+            return true
+        }
+    }
+    return !regexOnlySyntax.MatchString(pattern) &&
+        (expressionOptional.MatchString(pattern) || expressionAlternation.MatchString(pattern))
+}
+
+// Compiles a Cucumber Expression such as "I have {int} cuke(s) in my
+// belly/stomach" into an anchored regular expression with one capture
+// group per parameter, returned in order.
+func compileExpression(expr string, types *parameterTypes) (*re.Regexp, []*parameterType, error) {
+    c := &expressionCompiler{types: types.byName}
+    pattern := "^"
+    for _, segment := range splitExpression(expr) {
+        compiled, err := c.compileSegment(segment)
+        if err != nil {
+            return nil, nil, fmt.Errorf("invalid cucumber expression %q: %s", expr, err)
+        }
+        pattern += compiled
+    }
+    r, err := re.Compile(pattern + "$")
+    return r, c.params, err
+}
+
+type expressionCompiler struct {
+    types map[string]*parameterType
+    params []*parameterType
+}
+
+// Splits expr into runs of whitespace and runs of text, keeping
+// whitespace within () and {} as part of the text.
+func splitExpression(expr string) []string {
+    segments := []string{}
+    runes := []rune(expr)
+    start, depth := 0, 0
+    for i := 0; i < len(runes); i++ {
+        if depth == 0 && i > start && unicode.IsSpace(runes[i]) != unicode.IsSpace(runes[i - 1]) {
+            segments = append(segments, string(runes[start:i]))
+            start = i
+        }
+        switch runes[i] {
+        case '\\':
+            i++
+        case '(', '{':
+            depth++
+        case ')', '}':
+            depth--
+        }
+    }
+    if start < len(runes) {
+        segments = append(segments, string(runes[start:]))
+    }
+    return segments
+}
+
+// Splits text on each '/' that is neither escaped nor inside () or {}.
+func splitAlternatives(text string) []string {
+    alternatives := []string{}
+    runes := []rune(text)
+    start, depth := 0, 0
+    for i := 0; i < len(runes); i++ {
+        switch runes[i] {
+        case '\\':
+            i++
+        case '(', '{':
+            depth++
+        case ')', '}':
+            depth--
+        case '/':
+            if depth == 0 {
+                alternatives = append(alternatives, string(runes[start:i]))
+                start = i + 1
+            }
+        }
+    }
+    return append(alternatives, string(runes[start:]))
+}
+
+func (c *expressionCompiler) compileSegment(segment string) (string, error) {
+    if strings.TrimSpace(segment) == "" {
+        return re.QuoteMeta(segment), nil
+    }
+    alternatives := splitAlternatives(segment)
+    if len(alternatives) == 1 {
+        return c.compileText(segment, "")
+    }
+    compiled := []string{}
+    for _, alternative := range alternatives {
+        if alternative == "" {
+            return "", fmt.Errorf("empty alternative in %q", segment)
+        }
+        s, err := c.compileText(alternative, "an alternative")
+        if err != nil {
+            return "", err
+        }
+        compiled = append(compiled, s)
+    }
+    return "(?:" + strings.Join(compiled, "|") + ")", nil
+}
+
+// Compiles text without whitespace or alternation. Parameters are only
+// allowed when within is empty, otherwise it names the enclosing
+// construct for the error message.
+func (c *expressionCompiler) compileText(text string, within string) (string, error) {
+    pattern := ""
+    runes := []rune(text)
+    for i := 0; i < len(runes); i++ {
+        switch runes[i] {
+        case '\\':
+            if i + 1 < len(runes) {
+                i++
+            }
+            pattern += re.QuoteMeta(string(runes[i]))
+        case '{':
+            end := indexRune(runes, i, '}')
+            if end < 0 {
+                return "", fmt.Errorf("missing } in %q", text)
+            }
+            name := string(runes[i + 1:end])
+            pt, ok := c.types[name]
+            if !ok {
+                return "", fmt.Errorf("undefined parameter type {%s}", name)
+            }
+            if within != "" {
+                return "", fmt.Errorf("parameter {%s} is not allowed within %s", name, within)
+            }
+            c.params = append(c.params, pt)
+            pattern += "(" + pt.regexp + ")"
+            i = end
+        case '(':
+            end := indexRune(runes, i, ')')
+            if end < 0 {
+                return "", fmt.Errorf("missing ) in %q", text)
+            }
+            optional, err := c.compileText(string(runes[i + 1:end]), "optional text")
+            if err != nil {
+                return "", err
+            }
+            pattern += "(?:" + optional + ")?"
+            i = end
+        default:
+            pattern += re.QuoteMeta(string(runes[i]))
+        }
+    }
+    return pattern, nil
+}
+
+// Index of the first unescaped r after position from, or -1.
+func indexRune(runes []rune, from int, r rune) int {
+    for i := from + 1; i < len(runes); i++ {
+        if runes[i] == '\\' {
+            i++
+        } else if runes[i] == r {
+            return i
+        }
+    }
+    return -1
+}
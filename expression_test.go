@@ -0,0 +1,107 @@
+package gherkin
+
+import (
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func matchExpression(t *testing.T, expr, text string) []string {
+    r, _, err := compileExpression(expr, defaultParameterTypes())
+    AssertThat(t, err, Equals(nil))
+    return r.FindStringSubmatch(text)
+}
+
+func TestExpressionMatchesLiteralText(t *testing.T) {
+    AssertThat(t, matchExpression(t, "I am hungry.", "I am hungry."), Equals([]string{"I am hungry."}))
+    AssertThat(t, matchExpression(t, "I am hungry.", "I am hungry!") == nil, IsTrue)
+}
+
+func TestExpressionCapturesParameters(t *testing.T) {
+    m := matchExpression(t, "I have {int} cukes in my {word}", "I have 42 cukes in my belly")
+
+    AssertThat(t, m[1:], Equals([]string{"42", "belly"}))
+}
+
+func TestExpressionSupportsOptionalText(t *testing.T) {
+    AssertThat(t, matchExpression(t, "I have {int} cuke(s)", "I have 1 cuke") == nil, IsFalse)
+    AssertThat(t, matchExpression(t, "I have {int} cuke(s)", "I have 2 cukes") == nil, IsFalse)
+}
+
+func TestExpressionSupportsAlternation(t *testing.T) {
+    AssertThat(t, matchExpression(t, "in my belly/stomach", "in my stomach") == nil, IsFalse)
+    AssertThat(t, matchExpression(t, "in my belly/stomach", "in my belly") == nil, IsFalse)
+    AssertThat(t, matchExpression(t, "in my belly/stomach", "in my bellystomach") == nil, IsTrue)
+}
+
+func TestExpressionSupportsEscapes(t *testing.T) {
+    AssertThat(t, matchExpression(t, `a \(literal\) \{int\}`, "a (literal) {int}") == nil, IsFalse)
+}
+
+func TestInvalidExpressions(t *testing.T) {
+    for _, expr := range []string{"{unknown}", "a {int", "cuke(s", "a/{int}", "cuke({int})", "a//b"} {
+        _, _, err := compileExpression(expr, defaultParameterTypes())
+        AssertThat(t, err == nil, IsFalse)
+    }
+}
+
+func TestDetectsCucumberExpressions(t *testing.T) {
+    types := defaultParameterTypes()
+    AssertThat(t, isCucumberExpression("I have {int} cukes", types), IsTrue)
+    AssertThat(t, isCucumberExpression("^I have {int} cukes$", types), IsFalse)
+    AssertThat(t, isCucumberExpression("(thing)", types), IsFalse)
+    AssertThat(t, isCucumberExpression("a{2}", types), IsFalse)
+    AssertThat(t, isCucumberExpression("I eat a cuke(s)", types), IsTrue)
+    AssertThat(t, isCucumberExpression("in my belly/stomach", types), IsTrue)
+    AssertThat(t, isCucumberExpression(`I have (\d+) cuke(s)`, types), IsFalse)
+    AssertThat(t, isCucumberExpression("^in my belly/stomach$", types), IsFalse)
+}
+
+func TestUnanchoredOptionalTextAndAlternationAreExpressions(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    err := g.RegisterStepDef("I eat a cuke(s) in my belly/stomach", func(w *World, ctx *Context) {
+        ctx.timesRun++
+    })
+    AssertThat(t, err, Equals(nil))
+
+    g.Execute(`Feature:
+        Scenario:
+            Given I eat a cuke in my belly
+            And I eat a cukes in my stomach
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(2))
+}
+
+func TestStepDefsAcceptCucumberExpressions(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    err := g.RegisterStepDef("I have {int} cuke(s) named {string}", func(w *World, ctx *Context, n int, name string) {
+        ctx.timesRun = n
+        ctx.captured = name
+    })
+    AssertThat(t, err, Equals(nil))
+
+    g.Execute(`Feature:
+        Scenario:
+            Given I have 3 cukes named "Bob \"the\" cuke"
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(3))
+    AssertThat(t, c.captured, Equals(`Bob "the" cuke`))
+}
+
+func TestExplicitExpressionStepDefWithoutParameters(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterExpressionStepDef("I eat cuke(s)", func(w *World, ctx *Context) { ctx.timesRun++ })
+    g.RegisterRegexStepDef("^unused$", func(w *World, ctx *Context) { })
+
+    g.Execute(`Feature:
+        Scenario:
+            Given I eat cuke
+            And I eat cukes
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(2))
+}
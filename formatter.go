@@ -0,0 +1,163 @@
+package gherkin
+
+import (
+    "time"
+)
+
+// Receives the events of a run, in order. Add formatters to a Runner
+// with AddFormatter(). A run is a call to Execute(), ExecuteFeature(),
+// Run(), RunFeature(), RunT() or RunFeatureT().
+type Formatter interface {
+    RunStarted(glue *Glue)
+    // The uri is the feature file, or empty for Execute().
+    FeatureStarted(feature *Feature, uri string)
+    ScenarioStarted(scenario *ScenarioInfo)
+    // Sent for every step, including skipped and background steps.
+    StepFinished(step *StepInfo, result *StepResult)
+    // Sent for every Before, After, BeforeStep and AfterStep hook run;
+    // result.Err is why a failed hook failed.
+    HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult)
+    // ScenarioInfo.Status holds the outcome of the scenario.
+    ScenarioFinished(scenario *ScenarioInfo)
+    FeatureFinished(feature *Feature)
+    RunFinished(rpt Report)
+}
+
+// The step definitions and hooks registered with the Runner, in
+// registration order.
+type Glue struct {
+    // Without arguments.
+    StepDefs []StepMatch
+    Hooks []*HookInfo
+}
+
+// The outcome of a step or hook.
+type StepResult struct {
+    Status Status
+    Duration time.Duration
+    // When the step or hook started; zero for steps that weren't run.
+    Started time.Time
+    // Why a failed, undefined or ambiguous step didn't pass.
+    Err error
+    // What was written for the step, e.g. by World.Errorf().
+    Output string
+}
+
+// The first tag with the name among those the scenario inherits from the
+// feature, its rule, its definition and its examples, or nil.
+func declaredTag(feature *Feature, scenario *ScenarioInfo, name string) *Tag {
+    declared := append([]*Tag{}, feature.Tags...)
+    if scenario.Rule != nil {
+        declared = append(declared, scenario.Rule.Tags...)
+    }
+    switch def := scenario.Definition.(type) {
+    case *Scenario:
+        declared = append(declared, def.Tags...)
+    case *ScenarioOutline:
+        declared = append(declared, def.Tags...)
+    }
+    if scenario.Examples != nil {
+        declared = append(declared, scenario.Examples.Tags...)
+    }
+    for _, t := range declared {
+        if t.Name == name {
+            return t
+        }
+    }
+    return nil
+}
+
+// Sends each event to several formatters.
+type formatters []Formatter
+
+func (fs formatters) RunStarted(glue *Glue) {
+    for _, f := range fs {
+        f.RunStarted(glue)
+    }
+}
+
+func (fs formatters) FeatureStarted(feature *Feature, uri string) {
+    for _, f := range fs {
+        f.FeatureStarted(feature, uri)
+    }
+}
+
+func (fs formatters) ScenarioStarted(scenario *ScenarioInfo) {
+    for _, f := range fs {
+        f.ScenarioStarted(scenario)
+    }
+}
+
+func (fs formatters) StepFinished(step *StepInfo, result *StepResult) {
+    for _, f := range fs {
+        f.StepFinished(step, result)
+    }
+}
+
+func (fs formatters) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    for _, f := range fs {
+        f.HookFinished(scenario, hook, result)
+    }
+}
+
+func (fs formatters) ScenarioFinished(scenario *ScenarioInfo) {
+    for _, f := range fs {
+        f.ScenarioFinished(scenario)
+    }
+}
+
+func (fs formatters) FeatureFinished(feature *Feature) {
+    for _, f := range fs {
+        f.FeatureFinished(feature)
+    }
+}
+
+func (fs formatters) RunFinished(rpt Report) {
+    for _, f := range fs {
+        f.RunFinished(rpt)
+    }
+}
+
+// Records the events of a scenario executed concurrently, to be sent on
+// in the original order once the scenarios before it are done.
+type recordedEvents struct {
+    events []func(Formatter)
+}
+
+func (rec *recordedEvents) replay(f Formatter) {
+    for _, event := range rec.events {
+        event(f)
+    }
+}
+
+func (rec *recordedEvents) RunStarted(glue *Glue) {
+    rec.events = append(rec.events, func(f Formatter) { f.RunStarted(glue) })
+}
+
+func (rec *recordedEvents) FeatureStarted(feature *Feature, uri string) {
+    rec.events = append(rec.events, func(f Formatter) { f.FeatureStarted(feature, uri) })
+}
+
+func (rec *recordedEvents) ScenarioStarted(scenario *ScenarioInfo) {
+    rec.events = append(rec.events, func(f Formatter) { f.ScenarioStarted(scenario) })
+}
+
+func (rec *recordedEvents) StepFinished(step *StepInfo, result *StepResult) {
+    rec.events = append(rec.events, func(f Formatter) { f.StepFinished(step, result) })
+}
+
+func (rec *recordedEvents) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    rec.events = append(rec.events, func(f Formatter) { f.HookFinished(scenario, hook, result) })
+}
+
+func (rec *recordedEvents) ScenarioFinished(scenario *ScenarioInfo) {
+    rec.events = append(rec.events, func(f Formatter) { f.ScenarioFinished(scenario) })
+}
+
+func (rec *recordedEvents) FeatureFinished(feature *Feature) {
+    rec.events = append(rec.events, func(f Formatter) { f.FeatureFinished(feature) })
+}
+
+func (rec *recordedEvents) RunFinished(rpt Report) {
+    rec.events = append(rec.events, func(f Formatter) { f.RunFinished(rpt) })
+}
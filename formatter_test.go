@@ -0,0 +1,139 @@
+package gherkin
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strings"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+// Records each event as a line of text.
+type eventRecorder struct {
+    events []string
+}
+
+func (e *eventRecorder) add(format string, args ...interface{}) {
+    e.events = append(e.events, fmt.Sprintf(format, args...))
+}
+
+func (e *eventRecorder) RunStarted(glue *Glue) { e.add("run started") }
+func (e *eventRecorder) FeatureStarted(feature *Feature, uri string) { e.add("feature %s", feature.Name) }
+func (e *eventRecorder) ScenarioStarted(scenario *ScenarioInfo) { e.add("scenario %s", scenario.Name) }
+func (e *eventRecorder) StepFinished(step *StepInfo, result *StepResult) {
+    e.add("step %s %v %v", step.Text, result.Status, result.Err)
+}
+func (e *eventRecorder) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    e.add("hook %s %v %v", hook.Kind, result.Status, result.Err)
+}
+func (e *eventRecorder) ScenarioFinished(scenario *ScenarioInfo) { e.add("finished %s %v", scenario.Name, scenario.Status) }
+func (e *eventRecorder) FeatureFinished(feature *Feature) { e.add("feature finished") }
+func (e *eventRecorder) RunFinished(rpt Report) { e.add("run finished %d", rpt.scenarioCount) }
+
+// Executes the feature with a formatter from newFormatter and returns
+// what it wrote, also decoded into v with unmarshal unless v is nil.
+func formatFeature(t *testing.T, g *Runner, newFormatter func(io.Writer) Formatter,
+    unmarshal func([]byte, interface{}) error, v interface{}, feature string) string {
+    var buf bytes.Buffer
+    g.AddFormatter(newFormatter(&buf))
+    g.Execute(feature, &Context{})
+    if v != nil {
+        AssertThat(t, unmarshal(buf.Bytes(), v), Equals(nil))
+    }
+    return buf.String()
+}
+
+func TestFormatterReceivesEventsInOrder(t *testing.T) {
+    rec := &eventRecorder{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken") })
+    g.AddFormatter(rec)
+
+    g.Execute(`Feature: Events
+        Background:
+            Given pass
+        Scenario: Passing
+            Given pass
+        Scenario: Failing
+            Given fail
+            Then missing
+    `, &Context{})
+
+    AssertThat(t, rec.events, Equals([]string{
+        "run started",
+        "feature Events",
+        "scenario Passing",
+        "step pass passed <nil>",
+        "step pass passed <nil>",
+        "finished Passing passed",
+        "scenario Failing",
+        "step pass passed <nil>",
+        "step fail failed broken",
+        "step missing skipped <nil>",
+        "finished Failing failed",
+        "feature finished",
+        "run finished 2"}))
+}
+
+func TestFormatterReceivesFailedHooks(t *testing.T) {
+    rec := &eventRecorder{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.Before("", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no database") })
+    g.AddFormatter(rec)
+
+    g.Execute(`Feature:
+        Scenario: Hooked
+            Given a step
+    `, &Context{})
+
+    AssertThat(t, rec.events[3:6], Equals([]string{
+        "hook Before failed no database",
+        "step a step skipped <nil>",
+        "finished Hooked hook failed"}))
+}
+
+func TestFormattersReceiveConcurrentScenariosInOrder(t *testing.T) {
+    rec := &eventRecorder{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.SetConcurrency(4)
+    g.SetContextFactory(func() interface{} { return &Context{} })
+    g.AddFormatter(rec)
+
+    g.Execute(`Feature:
+        Scenario: one
+            Given a step
+        Scenario: two
+            Given a step
+        Scenario: three
+            Given a step
+    `, &Context{})
+
+    scenarios := []string{}
+    for _, event := range rec.events {
+        if strings.HasPrefix(event, "scenario ") {
+            scenarios = append(scenarios, event)
+        }
+    }
+    AssertThat(t, scenarios, Equals([]string{"scenario one", "scenario two", "scenario three"}))
+}
+
+func TestPlainFormatterWritesSummaryAtRunFinished(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.AddFormatter(NewPlainFormatter(&buf))
+
+    g.Execute(`Feature: Plain
+        Scenario: Only
+            Given a step
+    `, &Context{})
+
+    AssertThat(t, buf.String(), Equals("Feature: Plain\n" +
+        "        Scenario: Only\n" +
+        "            Given a step\n\t\n" +
+        "1 scenarios\n1 steps(1 passed)\n"))
+}
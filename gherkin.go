@@ -1,16 +1,49 @@
 // Support the Gherkin language, as found in Ruby's Cucumber and Python's Lettuce projects.
 package gherkin
 
-import "io"
-import matchers "github.com/tychofreeman/go-matchers"
+import (
+    "errors"
+    "flag"
+    "io"
+    "os"
+    "testing"
+    matchers "github.com/tychofreeman/go-matchers"
+)
 
 // Static Runner object to make creating tests easier
 var DefaultRunner = CreateRunner()
 
+// Environment variable holding a tag expression, used by Run() when the
+// -gherkin.tags flag is not given.
+const TagsEnvVar = "GHERKIN_TAGS"
+
+var tagsFlag = flag.String("gherkin.tags", "",
+    "only run scenarios matching this tag expression, e.g. \"@smoke and not @slow\"")
+
+var strictFlag = flag.Bool("gherkin.strict", false,
+    "fail on pending, undefined and ambiguous steps")
+
+func commandLineTagFilter() (TagExpression, error) {
+    expr := *tagsFlag
+    if expr == "" {
+        expr = os.Getenv(TagsEnvVar)
+    }
+    return ParseTagExpression(expr)
+}
+
+var (
+    // Returned by a step function, or raised by Pending(), to mark the
+    // step as pending and skip the rest of the scenario.
+    ErrPending = errors.New("pending")
+    // Returned by a step function to skip the step and the rest of the
+    // scenario.
+    ErrSkip = errors.New("skipped")
+)
+
 // Use this function to let the user know that this
 // test is not complete.
 func Pending() {
-    panic("Pending")
+    panic(ErrPending)
 }
 
 // Pass-through for Runner.SetSetUpFn()
@@ -23,25 +56,120 @@ func TearDown(teardown func()) {
     DefaultRunner.SetTearDownFn(teardown)
 }
 
+// Pass-through for Runner.SetContextFactory()
+func SetContextFactory(factory func() interface{}) {
+    DefaultRunner.SetContextFactory(factory)
+}
+
+// Pass-through for Runner.Before()
+func Before(tags string, hook interface{}) error {
+    return DefaultRunner.Before(tags, hook)
+}
+
+// Pass-through for Runner.BeforeWithOrder()
+func BeforeWithOrder(order int, tags string, hook interface{}) error {
+    return DefaultRunner.BeforeWithOrder(order, tags, hook)
+}
+
+// Pass-through for Runner.After()
+func After(tags string, hook interface{}) error {
+    return DefaultRunner.After(tags, hook)
+}
+
+// Pass-through for Runner.AfterWithOrder()
+func AfterWithOrder(order int, tags string, hook interface{}) error {
+    return DefaultRunner.AfterWithOrder(order, tags, hook)
+}
+
+// Pass-through for Runner.BeforeStep()
+func BeforeStep(tags string, hook interface{}) error {
+    return DefaultRunner.BeforeStep(tags, hook)
+}
+
+// Pass-through for Runner.AfterStep()
+func AfterStep(tags string, hook interface{}) error {
+    return DefaultRunner.AfterStep(tags, hook)
+}
+
+// Pass-through for Runner.BeforeAll()
+func BeforeAll(f func() error) {
+    DefaultRunner.BeforeAll(f)
+}
+
+// Pass-through for Runner.AfterAll()
+func AfterAll(f func() error) {
+    DefaultRunner.AfterAll(f)
+}
+
+// Pass-through for Runner.SetConcurrency()
+func SetConcurrency(n int) {
+    DefaultRunner.SetConcurrency(n)
+}
+
 // Pass-through for Runner.RegisterStepDef()
-func RegisterStepDef(pattern string, stepdef interface{}) {
-    DefaultRunner.RegisterStepDef(pattern, stepdef)
+func RegisterStepDef(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterStepDef(pattern, stepdef)
+}
+
+// Pass-through for Runner.RegisterRegexStepDef()
+func RegisterRegexStepDef(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterRegexStepDef(pattern, stepdef)
+}
+
+// Pass-through for Runner.RegisterExpressionStepDef()
+func RegisterExpressionStepDef(expr string, stepdef interface{}) error {
+    return DefaultRunner.RegisterExpressionStepDef(expr, stepdef)
+}
+
+// Pass-through for Runner.RegisterParameterType()
+func RegisterParameterType(name, regexp string, transformer interface{}) error {
+    return DefaultRunner.RegisterParameterType(name, regexp, transformer)
+}
+
+func Given(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterStepDef(pattern, stepdef)
+}
+
+func When(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterStepDef(pattern, stepdef)
+}
+
+func Then(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterStepDef(pattern, stepdef)
+}
+
+func And(pattern string, stepdef interface{}) error {
+    return DefaultRunner.RegisterStepDef(pattern, stepdef)
+}
+
+// Pass-through for Runner.SetTagFilter()
+func SetTagFilter(expr string) error {
+    return DefaultRunner.SetTagFilter(expr)
+}
+
+// Pass-through for Runner.SetStrictMatching()
+func SetStrictMatching(strict bool) {
+    DefaultRunner.SetStrictMatching(strict)
 }
 
-func Given(pattern string, stepdef interface{}) {
-    DefaultRunner.RegisterStepDef(pattern, stepdef)
+// Pass-through for Runner.SetDryRun()
+func SetDryRun(dryRun bool) {
+    DefaultRunner.SetDryRun(dryRun)
 }
 
-func When(pattern string, stepdef interface{}) {
-    DefaultRunner.RegisterStepDef(pattern, stepdef)
+// Pass-through for Runner.SetStrict()
+func SetStrict(strict bool) {
+    DefaultRunner.SetStrict(strict)
 }
 
-func Then(pattern string, stepdef interface{}) {
-    DefaultRunner.RegisterStepDef(pattern, stepdef)
+// Pass-through for Runner.SetContinueOnFailure()
+func SetContinueOnFailure(continueOnFailure bool) {
+    DefaultRunner.SetContinueOnFailure(continueOnFailure)
 }
 
-func And(pattern string, stepdef interface{}) {
-    DefaultRunner.RegisterStepDef(pattern, stepdef)
+// Pass-through for Runner.SetSnippetStyle()
+func SetSnippetStyle(style SnippetStyle) {
+    DefaultRunner.SetSnippetStyle(style)
 }
 
 // Pass-through for Runner.SetOutput()
@@ -49,8 +177,19 @@ func SetOutput(output io.Writer) {
     DefaultRunner.SetOutput(output)
 }
 
+// Pass-through for Runner.AddFormatter()
+func AddFormatter(f Formatter) {
+    DefaultRunner.AddFormatter(f)
+}
+
 // Pass-through for Runner.Run()
 // This should be called after everything else.
 func Run(t matchers.Errorable, ctx interface{}) {
     DefaultRunner.Run(t, ctx)
 }
+
+// Pass-through for Runner.RunT()
+// This should be called after everything else.
+func RunT(t *testing.T, ctx interface{}) {
+    DefaultRunner.RunT(t, ctx)
+}
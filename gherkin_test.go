@@ -1,6 +1,12 @@
 package gherkin
 
 import (
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
     "testing"
     . "github.com/tychofreeman/go-matchers"
 )
@@ -47,6 +53,7 @@ func assertMatchCalledOrNot(t *testing.T, step string, pattern string, isCalled
     }
 
     g := createWriterlessRunner()
+    g.SetContinueOnFailure(true)
     g.RegisterStepDef(pattern, f)
 
     ctx := &Context{}
@@ -71,7 +78,9 @@ func TestAvoidsNonMatchingMethod(t *testing.T) {
 }
 
 func TestCallsOnlyFirstMatchingMethod(t *testing.T) {
-    first := func(w *World, ctx *Context) { }
+    first := func(w *World, ctx *Context) {
+        ctx.timesRun++
+    }
     second := func(w *World, ctx *Context) {
         ctx.wasCalled = true
     }
@@ -80,7 +89,12 @@ func TestCallsOnlyFirstMatchingMethod(t *testing.T) {
     g := createWriterlessRunner()
     g.RegisterStepDef(".", first)
     g.RegisterStepDef(".", second)
-    g.Execute("Given only the first step is called", c)
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given only the first step is called
+    `, c)
+    AssertThat(t, rpt.Err(), Equals(nil))
+    AssertThat(t, c.timesRun, Equals(1))
     AssertThat(t, c.wasCalled, Equals(false))
 }
 
@@ -233,6 +247,21 @@ func TestErrorsIfTooFewFieldsInMultiLineStep(t *testing.T) {
             Then then`, c)
 }
 
+func TestReportsTooFewFieldsInMultiLineStepAsParseError(t *testing.T) {
+    g := createWriterlessRunner()
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given given
+                |name|addr|
+                |bob|
+            Then then`, &Context{})
+
+    errs, ok := rpt.Err().(ParseErrors)
+    AssertThat(t, ok, IsTrue)
+    AssertThat(t, len(errs), Equals(1))
+    AssertThat(t, errs[0].Location, Equals(Location{5, 17}))
+}
+
 func TestSupportsMultipleMultiLineStepsPerScenario(t *testing.T) {
     c := &Context{}
     g := createWriterlessRunner()
@@ -280,67 +309,114 @@ func TestAllowsAccessToFirstRegexCapture(t *testing.T) {
 }
 
 func TestFailsGracefullyWithOutOfBoundsRegexCaptures(t *testing.T) {
-    panicked := false
+    out := &bytes.Buffer{}
     g := createWriterlessRunner()
-    g.RegisterStepDef(".", func(w *World, ctx *Context, x string) { })
+    g.SetOutput(out)
+    g.RegisterStepDef("^no captures$", func(w *World, ctx *Context, x string) { })
 
-    func() {
-        defer func() {
-            r := recover()
-            AssertThat(t, r, Equals("Function type mismatch"))
-            panicked = true
-        }()
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given no captures
+    `, &Context{})
 
-        g.Execute(`Feature:
-            Scenario:
-                Given .
-        `, &Context{})
-    }()
+    AssertThat(t, rpt.failedSteps, Equals(1))
+    AssertThat(t, strings.Contains(out.String(), `step definition "^no captures$" (`), IsTrue)
+    AssertThat(t, strings.Contains(out.String(), "takes a doc string, but the step has none"), IsTrue)
+    AssertThat(t, strings.Contains(out.String(), "panic:"), IsFalse)
+}
+
+func TestRejectsInvalidFunctionTypeAtRegistration(t *testing.T) {
+    g := createWriterlessRunner()
+    err := g.RegisterStepDef("(.)", func(w *World, ctx *Context, x interface{}) { })
 
-    AssertThat(t, panicked, IsTrue)
+    AssertThat(t, err == nil, IsFalse)
+    AssertThat(t, strings.Contains(err.Error(), "argument 3 of type interface {} is not supported"), IsTrue)
+    AssertThat(t, len(g.steps), Equals(0))
 }
 
-func TestFailsGracefullyWithInvalidFunctionType(t *testing.T) {
-    panicked := false
+func TestRejectsInvalidPatternAtRegistration(t *testing.T) {
     g := createWriterlessRunner()
-    g.RegisterStepDef("(.)", func(w *World, ctx *Context, x interface{}) { })
+    err := g.RegisterStepDef("(unclosed", func(w *World, ctx *Context) { })
 
-    func() {
-        defer func() {
-            r := recover()
-            AssertThat(t, r, Equals("Function type not supported"))
-            panicked = true
-        }()
+    AssertThat(t, err == nil, IsFalse)
+    AssertThat(t, strings.HasPrefix(err.Error(), `step definition "(unclosed" (`), IsTrue)
+    AssertThat(t, strings.Contains(err.Error(), "gherkin_test.go:"), IsTrue)
+}
 
-        g.Execute(`Feature:
-            Scenario:
-                Given .
-        `, &Context{})
-    }()
+func TestRejectsMismatchedCaptureCountAtRegistration(t *testing.T) {
+    g := createWriterlessRunner()
+    err := g.RegisterStepDef("(\\d+) (\\d+) (\\d+)", func(w *World, ctx *Context, a, b int) { })
+
+    AssertThat(t, err == nil, IsFalse)
+}
+
+func TestRejectsFunctionsWithoutWorldAtRegistration(t *testing.T) {
+    g := createWriterlessRunner()
+    err := g.RegisterStepDef(".", func(ctx *Context) { })
+
+    AssertThat(t, err == nil, IsFalse)
+}
+
+type recordingErrorable struct {
+    errors []string
+}
 
-    AssertThat(t, panicked, IsTrue)
+func (e *recordingErrorable) Errorf(format string, args ...interface{}) {
+    e.errors = append(e.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRunFeatureReportsRejectedStepDefsWithoutRunning(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("(", func(w *World, ctx *Context) { })
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { ctx.wasRun = true })
+    rec := &recordingErrorable{}
+    g.RunFeature(rec, c, "does-not-matter.feature")
+
+    AssertThat(t, len(rec.errors), Equals(1))
+    AssertThat(t, c.wasRun, IsFalse)
 }
 
 func TestFailsGracefullyWithInvalidArguments(t *testing.T) {
-    panicked := false
     g := createWriterlessRunner()
     g.RegisterStepDef("(.)", func(w *World, ctx *Context, x int) {
         t.Fail()
     })
 
-    func() {
-        defer func() {
-            recover()
-            panicked = true
-        }()
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given x
+    `, &Context{})
 
-        g.Execute(`Feature:
-            Scenario:
-                Given x
-        `, &Context{})
-    }()
+    AssertThat(t, rpt.failedSteps, Equals(1))
+}
+
+func TestRecoversStepPanicsAndSkipsRestOfScenario(t *testing.T) {
+    out := &bytes.Buffer{}
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.SetOutput(out)
+    g.RegisterStepDef("^nil$", func(w *World, ctx *Context) {
+        var m map[string]*Context
+        m["x"].wasCalled = true
+    })
+    g.RegisterStepDef("^run$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given nil
+            Then run
+        Scenario:
+            Given run
+    `, c)
 
-    AssertThat(t, panicked, IsTrue)
+    AssertThat(t, c.timesRun, Equals(1))
+    AssertThat(t, rpt.failedSteps, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+    AssertThat(t, rpt.passedSteps, Equals(1))
+    AssertThat(t, strings.Contains(out.String(), "panic: runtime error: invalid memory address"), IsTrue)
+    AssertThat(t, strings.Contains(out.String(), "gherkin_test.go:"), IsTrue)
+    AssertThat(t, strings.Contains(out.String(), "reflect."), IsFalse)
 }
 
 func TestSupportsArguments(t *testing.T) {
@@ -381,7 +457,7 @@ func TestScenarioOutlineWithoutExampleDoesNotExecute(t *testing.T) {
 }
 
 func TestScenarioOutlineReplacesFieldWithValueInExample(t *testing.T) {
-    so := ScenarioOutline()
+    so := createScenarioOutline()
     so.AddStep(StepFromString(`<count> pops`))
     scenario := so.CreateForExample(map[string]string{"count":"5"})
 
@@ -389,7 +465,7 @@ func TestScenarioOutlineReplacesFieldWithValueInExample(t *testing.T) {
 }
 
 func TestScenarioOutlineReplacesManyFieldsWithValuesInExample(t *testing.T) {
-    so := ScenarioOutline()
+    so := createScenarioOutline()
     so.AddStep(StepFromString(`<count> <name>`))
     scenario := so.CreateForExample(map[string]string{"count":"5", "name":"pops"})
 
@@ -397,7 +473,7 @@ func TestScenarioOutlineReplacesManyFieldsWithValuesInExample(t *testing.T) {
 }
 
 func TestScenarioOutlineSupportsMultipleLines(t *testing.T) {
-    so := ScenarioOutline()
+    so := createScenarioOutline()
     so.AddStep(StepFromString(`<count> <name>`))
     so.AddStep(StepFromString(`<name> <type>`))
     scenario := so.CreateForExample(map[string]string{"count":"5", "name":"pops", "type":"music"})
@@ -434,6 +510,519 @@ func TestBackgroundDoesntExecuteBackgroundWhenRun(t *testing.T) {
    AssertThat(t, c.wasRun, IsFalse)
 }
 
-// Support PyStrings?
-// Support tags?
+func TestScenarioInheritsFeatureTags(t *testing.T) {
+    tags := []string{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { tags = w.Tags() })
+    g.Execute(`@feature
+    Feature:
+        @scenario @feature
+        Scenario:
+            Given .
+    `, &Context{})
+
+    AssertThat(t, tags, Equals([]string{"@feature", "@scenario"}))
+}
+
+func TestExampleRowsInheritExamplesTags(t *testing.T) {
+    tags := [][]string{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { tags = append(tags, w.Tags()) })
+    g.Execute(`Feature:
+        @outline
+        Scenario Outline:
+            Given <x>
+        @first
+        Examples:
+            |x|
+            |a|
+        @second
+        Examples:
+            |x|
+            |b|
+    `, &Context{})
+
+    AssertThat(t, tags, Equals([][]string{
+        []string{"@outline", "@first"},
+        []string{"@outline", "@second"},
+    }))
+}
+
+func TestBackgroundSeesScenarioTags(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^background$", func(w *World, ctx *Context) { ctx.wasCalled = w.HasTag("@tagged") })
+    g.Execute(`Feature:
+        Background:
+            Given background
+        @tagged
+        Scenario:
+            Then this
+    `, c)
+
+    AssertThat(t, c.wasCalled, IsTrue)
+}
+
+func TestFailingBackgroundSkipsScenarioSteps(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.SetOutput(&bytes.Buffer{})
+    g.RegisterStepDef("^background$", func(w *World, ctx *Context) { w.Errorf("broken") })
+    g.RegisterStepDef("^this$", func(w *World, ctx *Context) { ctx.wasCalled = true })
+    rpt := g.Execute(`Feature:
+        Background:
+            Given background
+        Scenario:
+            Then this
+    `, c)
+
+    AssertThat(t, c.wasCalled, IsFalse)
+    AssertThat(t, rpt.failedSteps, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+}
+
+func TestPassesDocStringToStep(t *testing.T) {
+    var docString *DocString
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { docString = w.DocString })
+    g.Execute(`Feature:
+        Scenario:
+            Given a request
+                """json
+                {"name": "bob"}
+                """
+    `, &Context{})
+
+    AssertThat(t, docString.Content, Equals(`{"name": "bob"}`))
+    AssertThat(t, docString.MediaType, Equals("json"))
+}
+
+func TestPassesDocStringAsTrailingArgument(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^a (\\w+) body$", func(w *World, ctx *Context, kind string, body string) {
+        ctx.captured = kind + ":" + body
+    })
+    g.Execute(`Feature:
+        Scenario:
+            Given a text body
+                ` + "```" + `
+                hello
+                ` + "```" + `
+    `, c)
+
+    AssertThat(t, c.captured, Equals("text:hello"))
+}
+
+func TestScenarioOutlineReplacesFieldsInDocString(t *testing.T) {
+    contents := []string{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context, body string) { contents = append(contents, body) })
+    g.Execute(`Feature:
+        Scenario Outline:
+            Given a body
+                """
+                name: <name>
+                """
+        Examples:
+            |name|
+            |bob|
+    `, &Context{})
+
+    AssertThat(t, contents, Equals([]string{"name: bob"}))
+}
+
 // Support reporting.
+
+func TestStrictMatchingReportsAmbiguousSteps(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.SetStrictMatching(true)
+    g.RegisterStepDef("^I have (\\d+) cukes$", func(w *World, ctx *Context, n int) { ctx.wasCalled = true })
+    g.RegisterStepDef("^I have (.*)$", func(w *World, ctx *Context, s string) { ctx.wasCalled = true })
+    g.RegisterStepDef("^then$", func(w *World, ctx *Context) { ctx.wasThenRun = true })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given I have 3 cukes
+            Then then
+    `, c)
+
+    AssertThat(t, c.wasCalled, IsFalse)
+    AssertThat(t, c.wasThenRun, IsFalse)
+    AssertThat(t, rpt.ambiguousSteps, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+}
+
+func TestCheckAmbiguitiesScansFeatures(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^I have (\\d+) cukes$", func(w *World, ctx *Context, n int) { })
+    g.RegisterStepDef("^I have (.*)$", func(w *World, ctx *Context, s string) { })
+    f := parse(t, `Feature:
+        Background:
+            Given I have 1 cukes
+        Scenario Outline:
+            Given I have <n>
+        Examples:
+            |n|
+            |2 cukes|
+            |many|
+    `)
+
+    ambiguous := g.CheckAmbiguities(f)
+
+    AssertThat(t, len(ambiguous), Equals(2))
+    AssertThat(t, ambiguous[0].Text, Equals("I have 1 cukes"))
+    AssertThat(t, ambiguous[0].Step.Line, Equals(3))
+    AssertThat(t, ambiguous[1].Text, Equals("I have 2 cukes"))
+    AssertThat(t, ambiguous[1].Step.Line, Equals(5))
+    AssertThat(t, len(ambiguous[0].StepDefs), Equals(2))
+    AssertThat(t, strings.Contains(ambiguous[0].StepDefs[0], "gherkin_test.go:"), IsTrue)
+}
+
+func TestContextFactoryGivesEachScenarioAFreshContext(t *testing.T) {
+    contexts := []*Context{}
+    g := createWriterlessRunner()
+    g.SetContextFactory(func() interface{} {
+        c := &Context{}
+        contexts = append(contexts, c)
+        return c
+    })
+    g.SetSetUpFn(func(ctx *Context) { ctx.setUpWasCalled = true })
+    g.SetTearDownFn(func(ctx *Context) { ctx.tearDownWasCalled = true })
+    g.RegisterStepDef("^background$", func(w *World, ctx *Context) { ctx.timesRun++ })
+    g.RegisterStepDef("^count$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    g.Execute(`Feature:
+        Background:
+            Given background
+        Scenario:
+            Given count
+        Scenario:
+            Given count
+    `, nil)
+
+    AssertThat(t, len(contexts), Equals(2))
+    for _, c := range contexts {
+        AssertThat(t, c.timesRun, Equals(2))
+        AssertThat(t, c.setUpWasCalled, IsTrue)
+        AssertThat(t, c.tearDownWasCalled, IsTrue)
+    }
+}
+
+func TestRunsScenariosConcurrentlyWithOrderedOutput(t *testing.T) {
+    out := &bytes.Buffer{}
+    g := createWriterlessRunner()
+    g.SetOutput(out)
+    g.SetConcurrency(4)
+    g.SetContextFactory(func() interface{} { return &Context{} })
+    started := make(chan bool, 3)
+    release := make(chan bool)
+    g.RegisterStepDef("^wait$", func(w *World, ctx *Context) {
+        started <- true
+        <-release
+    })
+    go func() {
+        for i := 0; i < 3; i++ {
+            <-started
+        }
+        close(release)
+    }()
+
+    rpt := g.Execute(`Feature:
+        Scenario: one
+            Given wait
+        Scenario: two
+            Given wait
+        Scenario: three
+            Given wait
+    `, nil)
+
+    AssertThat(t, rpt.passedSteps, Equals(3))
+    one, two, three := strings.Index(out.String(), "one"), strings.Index(out.String(), "two"), strings.Index(out.String(), "three")
+    AssertThat(t, one < two && two < three, IsTrue)
+}
+
+func TestWarnsThatConcurrencyNeedsAContextFactory(t *testing.T) {
+    out := &bytes.Buffer{}
+    g := createWriterlessRunner()
+    g.SetOutput(out)
+    g.SetConcurrency(4)
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given a step
+    `, &Context{})
+
+    AssertThat(t, rpt.passedSteps, Equals(1))
+    AssertThat(t, strings.Contains(out.String(),
+        "Warning: SetConcurrency(4) has no effect without SetContextFactory()\n"), IsTrue)
+}
+
+func TestRaisesPanicsOfConcurrentScenariosInTheCaller(t *testing.T) {
+    g := createWriterlessRunner()
+    g.SetConcurrency(2)
+    created := 0
+    lock := make(chan bool, 1)
+    g.SetContextFactory(func() interface{} {
+        lock <- true
+        created++
+        n := created
+        <-lock
+        if n == 2 {
+            panic("no context")
+        }
+        return &Context{}
+    })
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+
+    var recovered interface{}
+    func() {
+        defer func() { recovered = recover() }()
+        g.Execute(`Feature:
+            Scenario: one
+                Given a step
+            Scenario: two
+                Given a step
+            Scenario: three
+                Given a step
+        `, nil)
+    }()
+
+    AssertThat(t, recovered, Equals("no context"))
+    AssertThat(t, created, Equals(3))
+}
+
+func TestStepReturningErrorFails(t *testing.T) {
+    out := &bytes.Buffer{}
+    g := createWriterlessRunner()
+    g.SetOutput(out)
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) error { return fmt.Errorf("broken") })
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) error { return nil })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given pass
+            Then fail
+    `, &Context{})
+
+    AssertThat(t, rpt.passedSteps, Equals(1))
+    AssertThat(t, rpt.failedSteps, Equals(1))
+    AssertThat(t, strings.Contains(out.String(), "broken"), IsTrue)
+}
+
+func TestStepReturningContextReplacesIt(t *testing.T) {
+    var last *Context
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^replace$", func(w *World, ctx *Context) (*Context, error) {
+        return &Context{captured: "new"}, nil
+    })
+    g.RegisterStepDef("^check$", func(w *World, ctx *Context) { last = ctx })
+    g.SetTearDownFn(func(ctx *Context) { ctx.tearDownWasCalled = true })
+
+    g.Execute(`Feature:
+        Scenario:
+            Given replace
+            Then check
+    `, &Context{})
+
+    AssertThat(t, last.captured, Equals("new"))
+    AssertThat(t, last.tearDownWasCalled, IsTrue)
+}
+
+func TestSentinelErrorsMarkStepsPendingOrSkipped(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pending$", func(w *World, ctx *Context) error { return ErrPending })
+    g.RegisterStepDef("^skip$", func(w *World, ctx *Context) error { return fmt.Errorf("no browser: %w", ErrSkip) })
+    g.RegisterStepDef("^run$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given pending
+            Then run
+        Scenario:
+            Given skip
+            Then run
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(0))
+    AssertThat(t, rpt.pendingSteps, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(3))
+    AssertThat(t, rpt.failedSteps, Equals(0))
+}
+
+func TestRejectsStepFunctionsWithOtherResults(t *testing.T) {
+    g := createWriterlessRunner()
+
+    AssertThat(t, g.RegisterStepDef(".", func(w *World, ctx *Context) int { return 0 }) == nil, IsFalse)
+    AssertThat(t, g.RegisterStepDef(".", func(w *World, ctx *Context) (string, error) { return "", nil }) == nil, IsFalse)
+}
+
+func TestFailedAndUndefinedStepsSkipTheRest(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("failed") })
+    g.RegisterStepDef("^run$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given fail
+            Then run
+        Scenario:
+            Given undefined
+            Then run
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(0))
+    AssertThat(t, rpt.skippedSteps, Equals(2))
+}
+
+func TestContinueOnFailureRunsTheRest(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.SetContinueOnFailure(true)
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("failed") })
+    g.RegisterStepDef("^run$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given fail
+            Then run
+        Scenario:
+            Given undefined
+            Then run
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(2))
+    AssertThat(t, rpt.skippedSteps, Equals(0))
+}
+
+func TestStrictModeFailsOnIncompleteSteps(t *testing.T) {
+    for _, strict := range []bool{false, true} {
+        rec := &recordingErrorable{}
+        g := createWriterlessRunner()
+        g.SetOutput(&bytes.Buffer{})
+        g.SetStrict(strict)
+        g.SetContinueOnFailure(true)
+        g.RegisterStepDef("^pending$", func(w *World, ctx *Context) { Pending() })
+        dir, _ := ioutil.TempDir("", "gherkin")
+        filename := filepath.Join(dir, "strict.feature")
+        ioutil.WriteFile(filename, []byte("Feature:\n  Scenario:\n    Given undefined\n  Scenario:\n    Given pending\n"), 0644)
+
+        g.RunFeature(rec, &Context{}, filename)
+        os.RemoveAll(dir)
+
+        if strict {
+            AssertThat(t, rec.errors, Equals([]string{
+                filename + `:3:5: undefined step "Given undefined"`,
+                filename + `:5:5: pending step "Given pending"`}))
+        } else {
+            AssertThat(t, len(rec.errors), Equals(0))
+        }
+    }
+}
+
+func TestStrictModeFailsOnAmbiguousSteps(t *testing.T) {
+    rec := &recordingErrorable{}
+    g := createWriterlessRunner()
+    g.SetOutput(&bytes.Buffer{})
+    g.SetStrict(true)
+    g.RegisterStepDef(`^I have (\d+) cukes$`, func(w *World, ctx *Context, n int) { })
+    g.RegisterStepDef(`^I have (.*)$`, func(w *World, ctx *Context, what string) { })
+    dir, _ := ioutil.TempDir("", "gherkin")
+    defer os.RemoveAll(dir)
+    filename := filepath.Join(dir, "strict.feature")
+    ioutil.WriteFile(filename, []byte("Feature:\n  Scenario:\n    Given I have 3 cukes\n"), 0644)
+
+    g.RunFeature(rec, &Context{}, filename)
+
+    AssertThat(t, rec.errors, Equals([]string{filename + `:3:5: ambiguous step "Given I have 3 cukes"`}))
+}
+
+func TestStrictModeFailsOnIncompleteBackgroundSteps(t *testing.T) {
+    rec := &recordingErrorable{}
+    g := createWriterlessRunner()
+    g.SetOutput(&bytes.Buffer{})
+    g.SetStrict(true)
+    g.RegisterStepDef("^this$", func(w *World, ctx *Context) { })
+    dir, _ := ioutil.TempDir("", "gherkin")
+    defer os.RemoveAll(dir)
+    filename := filepath.Join(dir, "strict.feature")
+    ioutil.WriteFile(filename, []byte("Feature:\n  Background:\n    Given undefined\n  Scenario:\n    Then this\n"), 0644)
+
+    g.RunFeature(rec, &Context{}, filename)
+
+    AssertThat(t, rec.errors, Equals([]string{filename + `:3:5: undefined step "Given undefined"`}))
+}
+
+func TestDryRunChecksStepsWithoutCallingThem(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.SetDryRun(true)
+    g.SetSetUpFn(func(ctx *Context) { ctx.setUpWasCalled = true })
+    g.Before("", func(info *ScenarioInfo, ctx *Context) { ctx.wasRun = true })
+    g.RegisterStepDef("^I have (.+) cukes$", func(w *World, ctx *Context, n int) { ctx.wasCalled = true })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given I have 3 cukes
+            And I have many cukes
+            And I eat them
+    `, c)
+
+    AssertThat(t, c.wasCalled, IsFalse)
+    AssertThat(t, c.setUpWasCalled, IsFalse)
+    AssertThat(t, c.wasRun, IsFalse)
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+    AssertThat(t, rpt.failedSteps, Equals(1))
+    AssertThat(t, rpt.undefinedSteps, Equals(1))
+    AssertThat(t, len(rpt.snippets), Equals(1))
+}
+
+func TestDryRunDoesntCallContextFactory(t *testing.T) {
+    factoryCalled := false
+    g := createWriterlessRunner()
+    g.SetDryRun(true)
+    g.SetContextFactory(func() interface{} {
+        factoryCalled = true
+        return &Context{}
+    })
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given a step
+    `, &Context{})
+
+    AssertThat(t, factoryCalled, IsFalse)
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+}
+
+func TestDryRunReportsAmbiguousSteps(t *testing.T) {
+    g := createWriterlessRunner()
+    g.SetDryRun(true)
+    g.RegisterStepDef(`^I have (\d+) cukes$`, func(w *World, ctx *Context, n int) { })
+    g.RegisterStepDef(`^I have (.*)$`, func(w *World, ctx *Context, what string) { })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given I have 3 cukes
+    `, &Context{})
+
+    AssertThat(t, rpt.ambiguousSteps, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(0))
+}
+
+func TestReportsInvalidCommandLineTagExpressionVerbatim(t *testing.T) {
+    os.Setenv(TagsEnvVar, "@wip and (%d")
+    defer os.Unsetenv(TagsEnvVar)
+    rec := &recordingErrorable{}
+    g := createWriterlessRunner()
+
+    g.RunFeature(rec, &Context{}, "does-not-matter.feature")
+
+    AssertThat(t, len(rec.errors), Equals(1))
+    AssertThat(t, strings.Contains(rec.errors[0], "(%d"), IsTrue)
+}
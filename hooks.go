@@ -0,0 +1,168 @@
+package gherkin
+
+import (
+    "fmt"
+    "reflect"
+    "time"
+)
+
+// Describes the scenario a Before or After hook runs for.
+type ScenarioInfo struct {
+    Name string
+    Feature string
+    // The line of the scenario, or of the example row for an outline.
+    Line int
+    Tags []string
+    // The outcome of the scenario; only set for After hooks.
+    Status Status
+    // When the scenario started and, once it has, finished.
+    Started time.Time
+    Finished time.Time
+    Keyword string
+    // The *Scenario or *ScenarioOutline the scenario was compiled from.
+    Definition ScenarioDefinition
+    // The rule containing the scenario, if any.
+    Rule *Rule
+    // For a scenario outline, the examples and row the scenario was
+    // compiled from.
+    Examples *Examples
+    Row *TableRow
+}
+
+// Describes the step a BeforeStep or AfterStep hook runs for.
+type StepInfo struct {
+    Keyword string
+    // The step text, with scenario outline placeholders filled in.
+    Text string
+    // The pattern of the step definition the step matches, if any, and
+    // where its function is declared.
+    StepDef string
+    StepDefLocation string
+    // The outcome of the step; only set for AfterStep hooks.
+    Status Status
+    // The status, duration and error of the step; only set for AfterStep
+    // hooks.
+    Result *StepResult
+    Scenario *ScenarioInfo
+    // The parsed step; nil for steps not read from a feature.
+    Step *Step
+    // The data table rows, header included, and the doc string of the
+    // step, with scenario outline placeholders filled in.
+    DataTable [][]string
+    DocString *DocString
+    // The background the step belongs to, or nil for scenario steps.
+    Background *Background
+    // Every step definition the step matches, in registration order.
+    Matches []StepMatch
+}
+
+// Describes a hook for formatters.
+type HookInfo struct {
+    // Before, After, BeforeStep or AfterStep.
+    Kind string
+    // Where the hook function is declared.
+    Location string
+}
+
+// A step definition matching a step.
+type StepMatch struct {
+    // The regular expression or, if Expression is set, the Cucumber
+    // Expression of the step definition.
+    Pattern string
+    Expression bool
+    Location string
+    // One per capture group.
+    Arguments []StepArgument
+}
+
+// The text captured by a group of the pattern, and its byte offset in
+// the step text; -1 when the group didn't participate in the match.
+type StepArgument struct {
+    Value string
+    Start int
+}
+
+// A function run around each scenario or step matching a tag expression.
+type hook struct {
+    tags TagExpression
+    order int
+    f interface{}
+    location string
+}
+
+var (
+    scenarioInfoType = reflect.TypeOf(&ScenarioInfo{})
+    stepInfoType = reflect.TypeOf(&StepInfo{})
+)
+
+// Hooks are func(info, context), optionally returning an error, where
+// info is a *gherkin.ScenarioInfo or *gherkin.StepInfo.
+func createHook(order int, tags string, f interface{}, infoType reflect.Type) (hook, error) {
+    expr, err := ParseTagExpression(tags)
+    if err != nil {
+        return hook{}, fmt.Errorf("hook (%s): %s", funcLocation(f), err)
+    }
+    t := reflect.TypeOf(f)
+    if t == nil || t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != infoType ||
+        t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != errorType) {
+        return hook{}, fmt.Errorf("hook (%s): must be func(%v, context) with an optional error result, not %v",
+            funcLocation(f), infoType, t)
+    }
+    return hook{tags: expr, order: order, f: f, location: funcLocation(f)}, nil
+}
+
+// Inserts h after the hooks of lower or equal order.
+func insertHook(hooks []hook, h hook) []hook {
+    i := len(hooks)
+    for i > 0 && hooks[i - 1].order > h.order {
+        i--
+    }
+    hooks = append(hooks, hook{})
+    copy(hooks[i + 1:], hooks[i:])
+    hooks[i] = h
+    return hooks
+}
+
+// Calls the hook, turning a returned error or a panic into an error.
+func (h hook) call(info interface{}, ctx interface{}) (err error) {
+    defer func() {
+        if rec := recover(); rec != nil {
+            err = fmt.Errorf("%v", rec)
+        }
+    }()
+    f := reflect.ValueOf(h.f)
+    c := reflect.ValueOf(ctx)
+    if ctx == nil {
+        c = reflect.Zero(f.Type().In(1))
+    }
+    out := f.Call([]reflect.Value{reflect.ValueOf(info), c})
+    if len(out) == 1 && !out[0].IsNil() {
+        return out[0].Interface().(error)
+    }
+    return nil
+}
+
+// Runs the hooks whose tag expression matches the tags, in the given
+// direction, and returns how many failed. The result of each is sent to
+// the formatter.
+func runHooks(hooks []hook, reverse bool, kind string, info interface{}, tags []string, env *execution) int {
+    failed := 0
+    for i := range hooks {
+        h := hooks[i]
+        if reverse {
+            h = hooks[len(hooks) - 1 - i]
+        }
+        if !h.tags.Evaluate(tags) {
+            continue
+        }
+        started := time.Now()
+        err := h.call(info, env.ctx)
+        result := &StepResult{Status: StatusPassed, Duration: time.Since(started), Started: started, Err: err}
+        if err != nil {
+            failed++
+            result.Status = StatusFailed
+        }
+        env.hookFinished(&HookInfo{Kind: kind, Location: h.location}, result)
+    }
+    return failed
+}
@@ -0,0 +1,267 @@
+package gherkin
+
+import (
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+var hookFeature = `Feature: Hooks
+        @db
+        Scenario: with db
+            Given pass
+        Scenario: without db
+            Given fail
+    `
+
+func TestRunsTaggedHooksInOrder(t *testing.T) {
+    calls := []string{}
+    record := func(name string) func(*ScenarioInfo, *Context) {
+        return func(info *ScenarioInfo, ctx *Context) {
+            calls = append(calls, name + " " + info.Name)
+        }
+    }
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("failed") })
+    g.BeforeWithOrder(2, "", record("second"))
+    g.BeforeWithOrder(1, "@db", record("first"))
+    g.After("", record("after"))
+
+    g.Execute(hookFeature, &Context{})
+
+    AssertThat(t, calls, Equals([]string{
+        "first with db", "second with db", "after with db",
+        "second without db", "after without db"}))
+}
+
+func TestAfterHooksReceiveScenarioInfo(t *testing.T) {
+    infos := []ScenarioInfo{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("failed") })
+    definitions := []ScenarioDefinition{}
+    g.After("", func(info *ScenarioInfo, ctx *Context) {
+        definitions = append(definitions, info.Definition)
+        copied := *info
+        copied.Definition = nil
+        AssertThat(t, copied.Started.IsZero(), IsFalse)
+        copied.Started = time.Time{}
+        infos = append(infos, copied)
+    })
+
+    feature := parse(t, hookFeature)
+    g.ExecuteFeature(feature, &Context{})
+
+    AssertThat(t, infos, Equals([]ScenarioInfo{
+        {Name: "with db", Feature: "Hooks", Line: 3, Tags: []string{"@db"}, Status: StatusPassed, Keyword: "Scenario"},
+        {Name: "without db", Feature: "Hooks", Line: 5, Tags: []string{}, Status: StatusFailed, Keyword: "Scenario"}}))
+    AssertThat(t, definitions, Equals([]ScenarioDefinition{feature.Scenarios[0], feature.Scenarios[1]}))
+}
+
+func TestFailingBeforeHookSkipsSteps(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { ctx.wasCalled = true })
+    g.Before("@db", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no database") })
+    status := StatusPassed
+    g.After("@db", func(info *ScenarioInfo, ctx *Context) { status = info.Status })
+
+    rpt := g.Execute(`Feature:
+        @db
+        Scenario:
+            Given a step
+    `, c)
+
+    AssertThat(t, c.wasCalled, IsFalse)
+    AssertThat(t, rpt.failedHooks, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(1))
+    AssertThat(t, status, Equals(StatusHookFailed))
+}
+
+func TestFailingBeforeHookSkipsBackgroundSteps(t *testing.T) {
+    rec := &eventRecorder{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.Before("", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no database") })
+    g.AddFormatter(rec)
+
+    rpt := g.Execute(`Feature:
+        Background:
+            Given first
+            And second
+        Scenario: Hooked
+            Then third
+    `, &Context{})
+
+    AssertThat(t, rpt.skippedSteps, Equals(3))
+    AssertThat(t, rec.events[4:7], Equals([]string{
+        "step first skipped <nil>",
+        "step second skipped <nil>",
+        "step third skipped <nil>"}))
+}
+
+func TestTearDownRunsWhenStepPanics(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { panic("boom") })
+    g.SetTearDownFn(func(ctx *Context) { ctx.tearDownWasCalled = true })
+    status := StatusPassed
+    g.After("", func(info *ScenarioInfo, ctx *Context) { status = info.Status })
+
+    func() {
+        defer func() { recover() }()
+        g.Execute(`Feature:
+            Scenario:
+                Given a step
+        `, c)
+    }()
+
+    AssertThat(t, c.tearDownWasCalled, IsTrue)
+    AssertThat(t, status, Equals(StatusFailed))
+}
+
+func TestRejectsInvalidHooks(t *testing.T) {
+    g := createWriterlessRunner()
+
+    AssertThat(t, g.Before("", func(ctx *Context) { }) == nil, IsFalse)
+    AssertThat(t, g.After("@a and", func(info *ScenarioInfo, ctx *Context) { }) == nil, IsFalse)
+    AssertThat(t, len(g.stepDefErrors), Equals(2))
+}
+
+func TestStepHooksReceiveStepInfo(t *testing.T) {
+    before, after := []StepInfo{}, []StepInfo{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^I have (\\d+) cukes$", func(w *World, ctx *Context, n int) { })
+    g.BeforeStep("", func(info *StepInfo, ctx *Context) { before = append(before, *info) })
+    g.AfterStep("", func(info *StepInfo, ctx *Context) { after = append(after, *info) })
+
+    g.Execute(`Feature:
+        Scenario: eating
+            Given I have 3 cukes
+            When I eat them
+    `, &Context{})
+
+    AssertThat(t, len(before), Equals(2))
+    AssertThat(t, before[0].Keyword, Equals("Given"))
+    AssertThat(t, before[0].Text, Equals("I have 3 cukes"))
+    AssertThat(t, before[0].StepDef, Equals("^I have (\\d+) cukes$"))
+    AssertThat(t, before[0].Scenario.Name, Equals("eating"))
+    AssertThat(t, after[0].Status, Equals(StatusPassed))
+    AssertThat(t, after[1].Status, Equals(StatusUndefined))
+}
+
+func TestFailingBeforeStepHookSkipsRemainingSteps(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { ctx.timesRun++ })
+    g.BeforeStep("", func(info *StepInfo, ctx *Context) {
+        if info.Text == "second" {
+            panic("no screenshot")
+        }
+    })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given first
+            And second
+            And third
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(1))
+    AssertThat(t, rpt.failedHooks, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(2))
+}
+
+func TestAfterStepHooksReceiveTheStepResult(t *testing.T) {
+    results := []StepResult{}
+    g := createWriterlessRunner()
+    g.SetContinueOnFailure(true)
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken") })
+    g.BeforeStep("", func(info *StepInfo, ctx *Context) {
+        if info.Result != nil {
+            panic("result set before the step")
+        }
+    })
+    g.AfterStep("", func(info *StepInfo, ctx *Context) { results = append(results, *info.Result) })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given pass
+            Then fail
+    `, &Context{})
+
+    AssertThat(t, rpt.failedHooks, Equals(0))
+    AssertThat(t, len(results), Equals(2))
+    AssertThat(t, results[0].Status, Equals(StatusPassed))
+    AssertThat(t, results[0].Err, Equals(nil))
+    AssertThat(t, results[1].Status, Equals(StatusFailed))
+    AssertThat(t, results[1].Err.Error(), Equals("broken"))
+}
+
+func TestFailingAfterStepHookSkipsRemainingSteps(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { ctx.timesRun++ })
+    g.AfterStep("", func(info *StepInfo, ctx *Context) error {
+        if info.Text == "first" {
+            return fmt.Errorf("no screenshot")
+        }
+        return nil
+    })
+    status := StatusPassed
+    g.After("", func(info *ScenarioInfo, ctx *Context) { status = info.Status })
+
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given first
+            And second
+            And third
+    `, c)
+
+    AssertThat(t, c.timesRun, Equals(1))
+    AssertThat(t, rpt.passedSteps, Equals(1))
+    AssertThat(t, rpt.failedHooks, Equals(1))
+    AssertThat(t, rpt.skippedSteps, Equals(2))
+    AssertThat(t, status, Equals(StatusHookFailed))
+}
+
+func TestSuiteHooksRunOnceAroundRun(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "gherkin")
+    defer os.RemoveAll(dir)
+    os.Mkdir(filepath.Join(dir, "features"), 0755)
+    for _, name := range []string{"a.feature", "b.feature"} {
+        ioutil.WriteFile(filepath.Join(dir, "features", name), []byte("Feature:\n  Scenario:\n    Given a step\n"), 0644)
+    }
+    wd, _ := os.Getwd()
+    os.Chdir(dir)
+    defer os.Chdir(wd)
+
+    calls := []string{}
+    g := createWriterlessRunner()
+    g.SetOutput(&bytes.Buffer{})
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { calls = append(calls, "step") })
+    g.BeforeAll(func() error { calls = append(calls, "before"); return nil })
+    g.AfterAll(func() error { calls = append(calls, "after"); return nil })
+    g.Run(&recordingErrorable{}, &Context{})
+
+    AssertThat(t, calls, Equals([]string{"before", "step", "step", "after"}))
+}
+
+func TestFailingBeforeAllSkipsFeatures(t *testing.T) {
+    ran := false
+    rec := &recordingErrorable{}
+    g := createWriterlessRunner()
+    g.BeforeAll(func() error { return fmt.Errorf("no database") })
+    g.AfterAll(func() error { ran = true; return nil })
+    g.RunFeature(rec, &Context{}, "does-not-exist.feature")
+
+    AssertThat(t, rec.errors, Equals([]string{"BeforeAll: no database"}))
+    AssertThat(t, ran, IsTrue)
+}
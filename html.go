@@ -0,0 +1,268 @@
+package gherkin
+
+import (
+    "fmt"
+    "html/template"
+    "io"
+    "strings"
+    "time"
+)
+
+// Writes the results of a run as a single HTML page without external
+// assets: a summary bar, then each feature and scenario as a collapsible
+// section, filterable by tag and status.
+type htmlFormatter struct {
+    output io.Writer
+    features []*htmlFeature
+    feature *htmlFeature
+    scenario *htmlScenario
+    started time.Time
+}
+
+type htmlFeature struct {
+    Keyword string
+    Name string
+    URI string
+    Description string
+    Tags []string
+    Scenarios []*htmlScenario
+}
+
+type htmlScenario struct {
+    Keyword string
+    Name string
+    Line int
+    Tags []string
+    Status string
+    Steps []*htmlStep
+    // The failed hooks, with their errors.
+    Hooks []string
+}
+
+type htmlStep struct {
+    Keyword string
+    Text string
+    Status string
+    Duration time.Duration
+    Error string
+    Table [][]string
+    DocString string
+    Background bool
+}
+
+// A part of the summary bar.
+type htmlSegment struct {
+    Status string
+    Count int
+    Percent float64
+}
+
+type htmlReport struct {
+    Title string
+    Generated string
+    Duration time.Duration
+    Scenarios int
+    Steps int
+    Segments []htmlSegment
+    Statuses []string
+    Features []*htmlFeature
+}
+
+// A formatter writing an HTML report to w once the run is finished.
+func NewHTMLFormatter(w io.Writer) Formatter {
+    return &htmlFormatter{output: w}
+}
+
+func (h *htmlFormatter) RunStarted(glue *Glue) {
+    h.features = nil
+    h.started = time.Now()
+}
+
+func (h *htmlFormatter) FeatureStarted(feature *Feature, uri string) {
+    h.feature = &htmlFeature{
+        Keyword: feature.Keyword,
+        Name: feature.Name,
+        URI: uri,
+        Description: strings.TrimSpace(feature.Description)}
+    for _, tag := range feature.Tags {
+        h.feature.Tags = append(h.feature.Tags, tag.Name)
+    }
+    h.features = append(h.features, h.feature)
+}
+
+func (h *htmlFormatter) ScenarioStarted(scenario *ScenarioInfo) {
+    h.scenario = &htmlScenario{
+        Keyword: scenario.Keyword,
+        Name: scenario.Name,
+        Line: scenario.Line,
+        Tags: scenario.Tags}
+    h.feature.Scenarios = append(h.feature.Scenarios, h.scenario)
+}
+
+func (h *htmlFormatter) StepFinished(step *StepInfo, result *StepResult) {
+    s := &htmlStep{
+        Keyword: step.Keyword,
+        Text: step.Text,
+        Status: result.Status.String(),
+        Duration: result.Duration,
+        Table: step.DataTable,
+        Background: step.Background != nil}
+    if result.Err != nil {
+        s.Error = result.Err.Error()
+    }
+    if step.DocString != nil {
+        s.DocString = step.DocString.Content
+    }
+    h.scenario.Steps = append(h.scenario.Steps, s)
+}
+
+func (h *htmlFormatter) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    if result.Status == StatusFailed {
+        h.scenario.Hooks = append(h.scenario.Hooks, fmt.Sprintf("%s (%s): %s", hook.Kind, hook.Location, result.Err))
+    }
+}
+
+func (h *htmlFormatter) ScenarioFinished(scenario *ScenarioInfo) {
+    h.scenario.Status = scenario.Status.String()
+}
+
+func (h *htmlFormatter) FeatureFinished(feature *Feature) {}
+
+func (h *htmlFormatter) RunFinished(rpt Report) {
+    counts := []struct {
+        status Status
+        count int
+    }{
+        {StatusPassed, rpt.passedSteps},
+        {StatusFailed, rpt.failedSteps},
+        {StatusPending, rpt.pendingSteps},
+        {StatusUndefined, rpt.undefinedSteps},
+        {StatusAmbiguous, rpt.ambiguousSteps},
+        {StatusSkipped, rpt.skippedSteps}}
+    report := htmlReport{
+        Title: "Feature report",
+        Generated: time.Now().Format("2006-01-02 15:04:05"),
+        Duration: time.Since(h.started),
+        Scenarios: rpt.scenarioCount,
+        Statuses: statusNames,
+        Features: h.features}
+    for _, c := range counts {
+        report.Steps += c.count
+    }
+    for _, c := range counts {
+        if c.count > 0 {
+            report.Segments = append(report.Segments,
+                htmlSegment{c.status.String(), c.count, 100 * float64(c.count) / float64(report.Steps)})
+        }
+    }
+    if err := htmlTemplate.Execute(h.output, report); err != nil {
+        fmt.Fprintf(h.output, "%s\n", err)
+    }
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+    // The CSS class of a status, e.g. status-hook-failed.
+    "class": func(status string) string { return "status-" + strings.Replace(status, " ", "-", -1) },
+    "join": strings.Join,
+}).Parse(htmlPage))
+
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 1em; }
+.bar { display: flex; height: 1.6em; border-radius: 4px; overflow: hidden; margin: 0.5em 0 1em; }
+.bar div { color: #fff; font-size: 0.8em; line-height: 2em; text-align: center; white-space: nowrap; overflow: hidden; }
+.filter { margin-bottom: 1em; }
+.filter input, .filter select { margin-right: 1em; }
+details { margin: 0.3em 0; }
+summary { cursor: pointer; padding: 0.2em; }
+.feature > summary { font-size: 1.2em; font-weight: bold; }
+.feature { border-bottom: 1px solid #ddd; padding-bottom: 0.5em; }
+.scenario { margin-left: 1.5em; border-left: 4px solid #ccc; padding-left: 0.5em; }
+.tags { color: #888; font-size: 0.85em; margin-left: 0.5em; }
+.steps { list-style: none; padding-left: 1em; margin: 0.3em 0; }
+.step { padding: 0.1em 0; }
+.step .keyword { font-weight: bold; }
+.step .duration { color: #999; font-size: 0.8em; margin-left: 0.5em; }
+.background { font-style: italic; }
+pre { background: #f6f6f6; padding: 0.5em; margin: 0.3em 0; white-space: pre-wrap; }
+pre.error { background: #fdecea; color: #a4262c; }
+table { border-collapse: collapse; margin: 0.3em 0; }
+td { border: 1px solid #ccc; padding: 0.1em 0.5em; }
+.status-passed { color: #2e7d32; border-color: #2e7d32; }
+.status-failed, .status-hook-failed { color: #c62828; border-color: #c62828; }
+.status-pending { color: #f9a825; border-color: #f9a825; }
+.status-undefined { color: #ef6c00; border-color: #ef6c00; }
+.status-ambiguous { color: #6a1b9a; border-color: #6a1b9a; }
+.status-skipped { color: #1565c0; border-color: #1565c0; }
+.bar .status-passed { background: #2e7d32; }
+.bar .status-failed { background: #c62828; }
+.bar .status-pending { background: #f9a825; }
+.bar .status-undefined { background: #ef6c00; }
+.bar .status-ambiguous { background: #6a1b9a; }
+.bar .status-skipped { background: #1565c0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{.Scenarios}} scenarios, {{.Steps}} steps{{range .Segments}}, {{.Count}} {{.Status}}{{end}}. Finished in {{.Duration}} at {{.Generated}}.</div>
+<div class="bar">{{range .Segments}}<div class="{{class .Status}}" style="width: {{printf "%.2f" .Percent}}%" title="{{.Count}} {{.Status}}">{{.Count}} {{.Status}}</div>{{end}}</div>
+<div class="filter">
+<label>Tag <input id="tag" type="text" placeholder="@tag"></label>
+<label>Status <select id="status"><option value="">all</option>{{range .Statuses}}<option value="{{.}}">{{.}}</option>{{end}}</select></label>
+</div>
+{{range .Features}}<details class="feature" open>
+<summary>{{.Keyword}}: {{.Name}}{{if .Tags}}<span class="tags">{{join .Tags " "}}</span>{{end}}</summary>
+{{if .URI}}<div class="meta">{{.URI}}</div>{{end}}
+{{if .Description}}<pre>{{.Description}}</pre>{{end}}
+{{range .Scenarios}}<details class="scenario {{class .Status}}" data-tags="{{join .Tags " "}}" data-status="{{.Status}}"{{if ne .Status "passed"}} open{{end}}>
+<summary class="{{class .Status}}">{{.Keyword}}: {{.Name}} <span class="tags">line {{.Line}}{{if .Tags}} {{join .Tags " "}}{{end}}</span></summary>
+<ul class="steps">
+{{range .Steps}}<li class="step{{if .Background}} background{{end}}"><span class="{{class .Status}}"><span class="keyword">{{.Keyword}}</span> {{.Text}}</span><span class="duration">{{.Status}}, {{.Duration}}</span>
+{{if .Table}}<table>{{range .Table}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+{{if .DocString}}<pre>{{.DocString}}</pre>{{end}}
+{{if .Error}}<pre class="error">{{.Error}}</pre>{{end}}
+</li>
+{{end}}</ul>
+{{range .Hooks}}<pre class="error">{{.}}</pre>{{end}}
+</details>
+{{end}}</details>
+{{end}}
+<script>
+(function() {
+    var tag = document.getElementById("tag");
+    var status = document.getElementById("status");
+    function filter() {
+        var wanted = tag.value.trim();
+        if (wanted && wanted.charAt(0) != "@") {
+            wanted = "@" + wanted;
+        }
+        var features = document.querySelectorAll(".feature");
+        for (var i = 0; i < features.length; i++) {
+            var scenarios = features[i].querySelectorAll(".scenario");
+            var shown = 0;
+            for (var j = 0; j < scenarios.length; j++) {
+                var s = scenarios[j];
+                var tags = s.getAttribute("data-tags").split(" ");
+                var show = (!wanted || tags.indexOf(wanted) >= 0) &&
+                    (!status.value || s.getAttribute("data-status") == status.value);
+                s.style.display = show ? "" : "none";
+                if (show) {
+                    shown++;
+                }
+            }
+            features[i].style.display = shown > 0 || scenarios.length == 0 ? "" : "none";
+        }
+    }
+    tag.addEventListener("input", filter);
+    status.addEventListener("change", filter);
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,53 @@
+package gherkin
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func TestHTMLFormatterWritesSelfContainedPage(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken <b>") })
+
+    page := formatFeature(t, g, NewHTMLFormatter, nil, nil, `Feature: Report
+  @smoke
+  Scenario: Passing
+    Given pass
+  Scenario: Failing
+    Given fail
+`)
+
+    AssertThat(t, strings.HasPrefix(page, "<!DOCTYPE html>"), IsTrue)
+    AssertThat(t, strings.Contains(page, "<script src"), IsFalse)
+    AssertThat(t, strings.Contains(page, `<link`), IsFalse)
+    AssertThat(t, strings.Contains(page, "2 scenarios, 2 steps, 1 passed, 1 failed."), IsTrue)
+    AssertThat(t, strings.Contains(page, `<div class="status-passed" style="width: 50.00%"`), IsTrue)
+    AssertThat(t, strings.Contains(page, `data-tags="@smoke" data-status="passed"`), IsTrue)
+    AssertThat(t, strings.Contains(page, `data-tags="" data-status="failed" open`), IsTrue)
+    AssertThat(t, strings.Contains(page, `<pre class="error">broken &lt;b&gt;</pre>`), IsTrue)
+}
+
+func TestHTMLFormatterRendersStepArgumentsAndHooks(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.After("", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no cleanup") })
+
+    page := formatFeature(t, g, NewHTMLFormatter, nil, nil, `Feature: Arguments
+  Scenario: Table and doc string
+    Given the users
+      | name |
+      | Ann  |
+    And the text
+      """
+      hello
+      """
+`)
+
+    AssertThat(t, strings.Contains(page, "<table><tr><td>name</td></tr><tr><td>Ann</td></tr></table>"), IsTrue)
+    AssertThat(t, strings.Contains(page, "<pre>hello</pre>"), IsTrue)
+    AssertThat(t, strings.Contains(page, `data-status="hook failed"`), IsTrue)
+    AssertThat(t, strings.Contains(page, ": no cleanup</pre>"), IsTrue)
+}
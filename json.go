@@ -0,0 +1,281 @@
+package gherkin
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+)
+
+// Writes the results of a run in the cucumber JSON format read by
+// cucumber-reporting and similar tools: an array of features, each with
+// an element per background and scenario, each with its steps.
+type jsonFormatter struct {
+    output io.Writer
+    features []*jsonFeature
+    feature *jsonFeature
+    tree *Feature
+    scenario *ScenarioInfo
+    // The element of the scenario, created at its first step.
+    element *jsonElement
+    background *jsonElement
+    before []*jsonHook
+    after []*jsonHook
+    // The failed step hooks of the next step, which is reported after
+    // its hooks have run.
+    stepBefore []*jsonHook
+    stepAfter []*jsonHook
+}
+
+type jsonFeature struct {
+    URI string `json:"uri"`
+    ID string `json:"id"`
+    Keyword string `json:"keyword"`
+    Name string `json:"name"`
+    Description string `json:"description"`
+    Line int `json:"line"`
+    Tags []jsonTag `json:"tags,omitempty"`
+    Elements []*jsonElement `json:"elements"`
+}
+
+type jsonElement struct {
+    ID string `json:"id,omitempty"`
+    Keyword string `json:"keyword"`
+    Name string `json:"name"`
+    Description string `json:"description"`
+    Line int `json:"line"`
+    Type string `json:"type"`
+    Tags []jsonTag `json:"tags,omitempty"`
+    Before []*jsonHook `json:"before,omitempty"`
+    Steps []*jsonStep `json:"steps"`
+    After []*jsonHook `json:"after,omitempty"`
+}
+
+type jsonTag struct {
+    Name string `json:"name"`
+    Line int `json:"line,omitempty"`
+}
+
+type jsonStep struct {
+    Keyword string `json:"keyword"`
+    Name string `json:"name"`
+    Line int `json:"line,omitempty"`
+    Rows []jsonRow `json:"rows,omitempty"`
+    DocString *jsonDocString `json:"doc_string,omitempty"`
+    Match *jsonMatch `json:"match,omitempty"`
+    Result jsonResult `json:"result"`
+    Before []*jsonHook `json:"before,omitempty"`
+    After []*jsonHook `json:"after,omitempty"`
+}
+
+type jsonRow struct {
+    Cells []string `json:"cells"`
+}
+
+type jsonDocString struct {
+    Value string `json:"value"`
+    ContentType string `json:"content_type,omitempty"`
+    Line int `json:"line"`
+}
+
+type jsonMatch struct {
+    Location string `json:"location"`
+}
+
+type jsonResult struct {
+    Status string `json:"status"`
+    // In nanoseconds.
+    Duration int64 `json:"duration,omitempty"`
+    ErrorMessage string `json:"error_message,omitempty"`
+}
+
+type jsonHook struct {
+    Match jsonMatch `json:"match"`
+    Result jsonResult `json:"result"`
+}
+
+// A formatter writing cucumber JSON to w once the run is finished.
+func NewJSONFormatter(w io.Writer) Formatter {
+    return &jsonFormatter{output: w}
+}
+
+// Lower case, with spaces replaced by dashes, as in cucumber ids.
+func jsonID(name string) string {
+    return strings.Replace(strings.ToLower(strings.TrimSpace(name)), " ", "-", -1)
+}
+
+func jsonTags(tags []*Tag) []jsonTag {
+    list := []jsonTag{}
+    for _, tag := range tags {
+        list = append(list, jsonTag{Name: tag.Name, Line: tag.Line})
+    }
+    return list
+}
+
+// The cucumber name of a status; a failed hook fails its scenario.
+func jsonStatus(status Status) string {
+    if status == StatusHookFailed {
+        return StatusFailed.String()
+    }
+    return status.String()
+}
+
+func (j *jsonFormatter) RunStarted(glue *Glue) {
+    j.features = []*jsonFeature{}
+}
+
+func (j *jsonFormatter) FeatureStarted(feature *Feature, uri string) {
+    j.tree = feature
+    j.feature = &jsonFeature{
+        URI: uri,
+        ID: jsonID(feature.Name),
+        Keyword: feature.Keyword,
+        Name: feature.Name,
+        Description: feature.Description,
+        Line: feature.Line,
+        Tags: jsonTags(feature.Tags),
+        Elements: []*jsonElement{}}
+    j.features = append(j.features, j.feature)
+}
+
+func (j *jsonFormatter) ScenarioStarted(scenario *ScenarioInfo) {
+    j.scenario = scenario
+    j.element, j.background = nil, nil
+    j.before, j.after = nil, nil
+    j.stepBefore, j.stepAfter = nil, nil
+}
+
+// The tags of the scenario, with the line of the tag it inherits each
+// one from.
+func (j *jsonFormatter) scenarioTags(scenario *ScenarioInfo) []jsonTag {
+    tags := []jsonTag{}
+    for _, name := range scenario.Tags {
+        tag := jsonTag{Name: name}
+        if t := declaredTag(j.tree, scenario, name); t != nil {
+            tag.Line = t.Line
+        }
+        tags = append(tags, tag)
+    }
+    return tags
+}
+
+// Creates the element of the current scenario, unless already created.
+func (j *jsonFormatter) scenarioElement() *jsonElement {
+    if j.element != nil {
+        return j.element
+    }
+    scenario := j.scenario
+    j.element = &jsonElement{
+        ID: j.feature.ID + ";" + jsonID(scenario.Name),
+        Keyword: scenario.Keyword,
+        Name: scenario.Name,
+        Line: scenario.Line,
+        Type: "scenario",
+        Tags: j.scenarioTags(scenario),
+        Steps: []*jsonStep{}}
+    switch def := scenario.Definition.(type) {
+    case *Scenario:
+        j.element.Description = def.Description
+    case *ScenarioOutline:
+        j.element.Name = def.Name
+        j.element.Description = def.Description
+        j.element.ID = fmt.Sprintf("%s;%s;%s;%d", j.feature.ID, jsonID(def.Name),
+            jsonID(scenario.Examples.Name), j.exampleRow(scenario))
+    }
+    j.feature.Elements = append(j.feature.Elements, j.element)
+    return j.element
+}
+
+// The row of the example within its table, counting the header as 1.
+func (j *jsonFormatter) exampleRow(scenario *ScenarioInfo) int {
+    for i, row := range scenario.Examples.TableBody {
+        if row == scenario.Row {
+            return i + 2
+        }
+    }
+    return 0
+}
+
+func (j *jsonFormatter) backgroundElement(bg *Background) *jsonElement {
+    if j.background == nil {
+        j.background = &jsonElement{
+            Keyword: bg.Keyword,
+            Name: bg.Name,
+            Description: bg.Description,
+            Line: bg.Line,
+            Type: "background",
+            Steps: []*jsonStep{}}
+        j.feature.Elements = append(j.feature.Elements, j.background)
+    }
+    return j.background
+}
+
+func (j *jsonFormatter) StepFinished(step *StepInfo, result *StepResult) {
+    s := &jsonStep{
+        Keyword: step.Keyword + " ",
+        Name: step.Text,
+        Result: jsonResult{Status: jsonStatus(result.Status), Duration: int64(result.Duration)},
+        Before: j.stepBefore,
+        After: j.stepAfter}
+    j.stepBefore, j.stepAfter = nil, nil
+    if result.Err != nil {
+        s.Result.ErrorMessage = result.Err.Error()
+    }
+    if step.StepDefLocation != "" {
+        s.Match = &jsonMatch{Location: step.StepDefLocation}
+    }
+    if step.Step != nil {
+        s.Line = step.Step.Line
+    }
+    for _, row := range step.DataTable {
+        s.Rows = append(s.Rows, jsonRow{Cells: row})
+    }
+    if doc := step.DocString; doc != nil {
+        s.DocString = &jsonDocString{Value: doc.Content, ContentType: doc.MediaType, Line: doc.Line}
+    }
+    var e *jsonElement
+    if step.Background != nil {
+        e = j.backgroundElement(step.Background)
+    } else {
+        e = j.scenarioElement()
+    }
+    e.Steps = append(e.Steps, s)
+}
+
+// Failed scenario hooks go in the scenario element, failed step hooks in
+// the step.
+func (j *jsonFormatter) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    if result.Status != StatusFailed {
+        return
+    }
+    h := &jsonHook{
+        Match: jsonMatch{Location: hook.Location},
+        Result: jsonResult{Status: StatusFailed.String(), ErrorMessage: result.Err.Error()}}
+    switch hook.Kind {
+    case "Before":
+        j.before = append(j.before, h)
+    case "After":
+        j.after = append(j.after, h)
+    case "BeforeStep":
+        j.stepBefore = append(j.stepBefore, h)
+    case "AfterStep":
+        j.stepAfter = append(j.stepAfter, h)
+    }
+}
+
+func (j *jsonFormatter) ScenarioFinished(scenario *ScenarioInfo) {
+    e := j.scenarioElement()
+    e.Before = j.before
+    e.After = j.after
+}
+
+func (j *jsonFormatter) FeatureFinished(feature *Feature) {}
+
+func (j *jsonFormatter) RunFinished(rpt Report) {
+    out, err := json.MarshalIndent(j.features, "", "  ")
+    if err != nil {
+        fmt.Fprintf(j.output, "%s\n", err)
+        return
+    }
+    fmt.Fprintf(j.output, "%s\n", out)
+}
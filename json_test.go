@@ -0,0 +1,130 @@
+package gherkin
+
+import (
+    "encoding/json"
+    "fmt"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func TestJSONFormatterWritesFeaturesElementsAndSteps(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken") })
+
+    features := []jsonFeature{}
+    formatFeature(t, g, NewJSONFormatter, json.Unmarshal, &features, `@web
+Feature: Login page
+  Background:
+    Given pass
+  @smoke
+  Scenario: Valid password
+    Given pass
+    Then fail
+    And missing
+`)
+
+    AssertThat(t, len(features), Equals(1))
+    f := features[0]
+    AssertThat(t, f.ID, Equals("login-page"))
+    AssertThat(t, f.Line, Equals(2))
+    AssertThat(t, f.Tags, Equals([]jsonTag{{Name: "@web", Line: 1}}))
+    AssertThat(t, len(f.Elements), Equals(2))
+
+    bg := f.Elements[0]
+    AssertThat(t, bg.Type, Equals("background"))
+    AssertThat(t, len(bg.Steps), Equals(1))
+    AssertThat(t, bg.Steps[0].Result.Status, Equals("passed"))
+
+    scen := f.Elements[1]
+    AssertThat(t, scen.Type, Equals("scenario"))
+    AssertThat(t, scen.ID, Equals("login-page;valid-password"))
+    AssertThat(t, scen.Keyword, Equals("Scenario"))
+    AssertThat(t, scen.Line, Equals(6))
+    AssertThat(t, scen.Tags, Equals([]jsonTag{{Name: "@web", Line: 1}, {Name: "@smoke", Line: 5}}))
+    AssertThat(t, len(scen.Steps), Equals(3))
+    AssertThat(t, scen.Steps[0].Keyword, Equals("Given "))
+    AssertThat(t, scen.Steps[0].Line, Equals(7))
+    AssertThat(t, scen.Steps[0].Match == nil, IsFalse)
+    AssertThat(t, scen.Steps[1].Result.Status, Equals("failed"))
+    AssertThat(t, scen.Steps[1].Result.ErrorMessage, Equals("broken"))
+    AssertThat(t, scen.Steps[2].Result.Status, Equals("skipped"))
+    AssertThat(t, scen.Steps[2].Match == nil, IsTrue)
+}
+
+func TestJSONFormatterWritesStepArguments(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+
+    features := []jsonFeature{}
+    formatFeature(t, g, NewJSONFormatter, json.Unmarshal, &features, `Feature: Arguments
+  Scenario Outline: Table and doc string
+    Given the users
+      | name  |
+      | <who> |
+    And the text
+      """json
+      hello <who>
+      """
+
+    Examples: Users
+      | who |
+      | Ann |
+`)
+
+    scen := features[0].Elements[0]
+    AssertThat(t, scen.ID, Equals("arguments;table-and-doc-string;users;2"))
+    AssertThat(t, scen.Name, Equals("Table and doc string"))
+    AssertThat(t, scen.Line, Equals(13))
+    AssertThat(t, scen.Steps[0].Rows, Equals([]jsonRow{{Cells: []string{"name"}}, {Cells: []string{"Ann"}}}))
+    AssertThat(t, *scen.Steps[1].DocString, Equals(jsonDocString{Value: "hello Ann", ContentType: "json", Line: 7}))
+}
+
+func TestJSONFormatterWritesFailedHooks(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.After("", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no cleanup") })
+
+    features := []jsonFeature{}
+    formatFeature(t, g, NewJSONFormatter, json.Unmarshal, &features, `Feature: Hooks
+  Scenario: Cleaned
+    Given a step
+`)
+
+    scen := features[0].Elements[0]
+    AssertThat(t, len(scen.Before), Equals(0))
+    AssertThat(t, len(scen.After), Equals(1))
+    AssertThat(t, scen.After[0].Result, Equals(jsonResult{Status: "failed", ErrorMessage: "no cleanup"}))
+}
+
+func TestJSONFormatterWritesFailedStepHooksInTheirStep(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.BeforeStep("@before", func(info *StepInfo, ctx *Context) error { return fmt.Errorf("no setup") })
+    g.AfterStep("", func(info *StepInfo, ctx *Context) error {
+        if info.Text == "second" {
+            return fmt.Errorf("no cleanup")
+        }
+        return nil
+    })
+
+    features := []jsonFeature{}
+    formatFeature(t, g, NewJSONFormatter, json.Unmarshal, &features, `Feature: Hooks
+  Scenario: Plain
+    Given first
+    And second
+  @before
+  Scenario: Tagged
+    Given first
+`)
+
+    plain := features[0].Elements[0]
+    AssertThat(t, len(plain.Before) + len(plain.After), Equals(0))
+    AssertThat(t, len(plain.Steps[0].After), Equals(0))
+    AssertThat(t, len(plain.Steps[1].After), Equals(1))
+    AssertThat(t, plain.Steps[1].After[0].Result, Equals(jsonResult{Status: "failed", ErrorMessage: "no cleanup"}))
+    tagged := features[0].Elements[1]
+    AssertThat(t, len(tagged.Before), Equals(0))
+    AssertThat(t, len(tagged.Steps[0].Before), Equals(1))
+    AssertThat(t, tagged.Steps[0].Before[0].Result, Equals(jsonResult{Status: "failed", ErrorMessage: "no setup"}))
+}
@@ -0,0 +1,136 @@
+package gherkin
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+)
+
+// Writes the results of a run as JUnit XML, with a testsuite per feature
+// and a testcase per scenario or example row.
+type junitFormatter struct {
+    output io.Writer
+    suites junitSuites
+    suite *junitSuite
+    testcase *junitTestcase
+    duration time.Duration
+    // The errors of the failed steps and hooks of the scenario.
+    errors []string
+    systemOut []string
+}
+
+type junitSuites struct {
+    XMLName xml.Name `xml:"testsuites"`
+    Suites []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+    Name string `xml:"name,attr"`
+    Tests int `xml:"tests,attr"`
+    Failures int `xml:"failures,attr"`
+    Skipped int `xml:"skipped,attr"`
+    Time string `xml:"time,attr"`
+    Testcases []*junitTestcase `xml:"testcase"`
+    duration time.Duration
+}
+
+type junitTestcase struct {
+    Classname string `xml:"classname,attr"`
+    Name string `xml:"name,attr"`
+    Time string `xml:"time,attr"`
+    Failure *junitFailure `xml:"failure,omitempty"`
+    Skipped *junitSkipped `xml:"skipped,omitempty"`
+    SystemOut string `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+    Message string `xml:"message,attr"`
+    Type string `xml:"type,attr"`
+    Body string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+    Message string `xml:"message,attr"`
+}
+
+// A formatter writing JUnit XML to w once the run is finished.
+func NewJUnitFormatter(w io.Writer) Formatter {
+    return &junitFormatter{output: w}
+}
+
+// Seconds, as JUnit XML expects.
+func junitTime(d time.Duration) string {
+    return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func (j *junitFormatter) RunStarted(glue *Glue) {
+    j.suites = junitSuites{}
+}
+
+func (j *junitFormatter) FeatureStarted(feature *Feature, uri string) {
+    name := feature.Name
+    if name == "" {
+        name = uri
+    }
+    j.suite = &junitSuite{Name: name}
+    j.suites.Suites = append(j.suites.Suites, j.suite)
+}
+
+func (j *junitFormatter) ScenarioStarted(scenario *ScenarioInfo) {
+    j.testcase = &junitTestcase{Classname: j.suite.Name, Name: scenario.Name}
+    j.duration = 0
+    j.errors, j.systemOut = nil, nil
+}
+
+func (j *junitFormatter) StepFinished(step *StepInfo, result *StepResult) {
+    j.duration += result.Duration
+    out := fmt.Sprintf("%s %s ... %s", step.Keyword, step.Text, result.Status)
+    if output := strings.TrimSpace(result.Output); output != "" {
+        out += "\n" + output
+    }
+    j.systemOut = append(j.systemOut, out)
+    if result.Err != nil && (result.Status == StatusFailed || result.Status == StatusAmbiguous) {
+        j.errors = append(j.errors, fmt.Sprintf("%s %s\n%s", step.Keyword, step.Text, result.Err))
+    }
+}
+
+func (j *junitFormatter) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    if result.Status == StatusFailed {
+        j.errors = append(j.errors, fmt.Sprintf("%s (%s)\n%s", hook.Kind, hook.Location, result.Err))
+    }
+}
+
+func (j *junitFormatter) ScenarioFinished(scenario *ScenarioInfo) {
+    tc := j.testcase
+    tc.Time = junitTime(j.duration)
+    tc.SystemOut = strings.Join(j.systemOut, "\n")
+    switch scenario.Status {
+    case StatusFailed, StatusHookFailed, StatusAmbiguous:
+        tc.Failure = &junitFailure{
+            Message: fmt.Sprintf("scenario %s", scenario.Status),
+            Type: scenario.Status.String(),
+            Body: strings.Join(j.errors, "\n\n")}
+        j.suite.Failures++
+    case StatusPending, StatusUndefined, StatusSkipped:
+        tc.Skipped = &junitSkipped{Message: scenario.Status.String()}
+        j.suite.Skipped++
+    }
+    j.suite.Tests++
+    j.suite.duration += j.duration
+    j.suite.Testcases = append(j.suite.Testcases, tc)
+}
+
+func (j *junitFormatter) FeatureFinished(feature *Feature) {
+    j.suite.Time = junitTime(j.suite.duration)
+}
+
+func (j *junitFormatter) RunFinished(rpt Report) {
+    out, err := xml.MarshalIndent(j.suites, "", "  ")
+    if err != nil {
+        fmt.Fprintf(j.output, "%s\n", err)
+        return
+    }
+    fmt.Fprintf(j.output, "%s%s\n", xml.Header, out)
+}
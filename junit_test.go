@@ -0,0 +1,75 @@
+package gherkin
+
+import (
+    "encoding/xml"
+    "fmt"
+    "strings"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func TestJUnitFormatterWritesSuitePerFeatureAndCasePerScenario(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pass$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken") })
+    g.RegisterStepDef("^pending$", func(w *World, ctx *Context) { Pending() })
+
+    suites := junitSuites{}
+    out := formatFeature(t, g, NewJUnitFormatter, xml.Unmarshal, &suites, `Feature: Checkout
+  Scenario: Passing
+    Given pass
+  Scenario: Failing
+    Given pass
+    When fail
+  Scenario: Pending
+    Given pending
+  Scenario Outline: Rows
+    Given <step>
+    Examples:
+      | step    |
+      | pass    |
+      | missing |
+`)
+
+    AssertThat(t, strings.HasPrefix(out, xml.Header), IsTrue)
+    AssertThat(t, len(suites.Suites), Equals(1))
+    suite := suites.Suites[0]
+    AssertThat(t, suite.Name, Equals("Checkout"))
+    AssertThat(t, suite.Tests, Equals(5))
+    AssertThat(t, suite.Failures, Equals(1))
+    AssertThat(t, suite.Skipped, Equals(2))
+    AssertThat(t, suite.Time == "", IsFalse)
+
+    names := []string{}
+    for _, tc := range suite.Testcases {
+        names = append(names, tc.Name)
+        AssertThat(t, tc.Classname, Equals("Checkout"))
+    }
+    AssertThat(t, names, Equals([]string{"Passing", "Failing", "Pending", "Rows #1", "Rows #2"}))
+
+    AssertThat(t, suite.Testcases[0].Failure == nil, IsTrue)
+    AssertThat(t, suite.Testcases[0].Skipped == nil, IsTrue)
+    AssertThat(t, suite.Testcases[0].SystemOut, Equals("Given pass ... passed"))
+    failure := suite.Testcases[1].Failure
+    AssertThat(t, failure.Type, Equals("failed"))
+    AssertThat(t, failure.Body, Equals("When fail\nbroken"))
+    AssertThat(t, suite.Testcases[1].SystemOut, Equals("Given pass ... passed\nWhen fail ... failed\nbroken"))
+    AssertThat(t, suite.Testcases[2].Skipped.Message, Equals("pending"))
+    AssertThat(t, suite.Testcases[4].Skipped.Message, Equals("undefined"))
+}
+
+func TestJUnitFormatterReportsFailedHooks(t *testing.T) {
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.Before("", func(info *ScenarioInfo, ctx *Context) error { return fmt.Errorf("no database") })
+
+    suites := junitSuites{}
+    formatFeature(t, g, NewJUnitFormatter, xml.Unmarshal, &suites, `Feature: Hooks
+  Scenario: Hooked
+    Given a step
+`)
+
+    failure := suites.Suites[0].Testcases[0].Failure
+    AssertThat(t, failure.Type, Equals("hook failed"))
+    AssertThat(t, strings.HasSuffix(failure.Body, "\nno database"), IsTrue)
+}
@@ -0,0 +1,513 @@
+package gherkin
+
+import (
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "runtime"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Writes the run as a stream of cucumber messages, one JSON envelope per
+// line, for tools such as the cucumber HTML formatter. The messages are
+// written once the run is finished, in the standard order. IDs are
+// numbered in the order the run comes across what they identify, so the
+// same suite always gets the same IDs.
+type messagesFormatter struct {
+    encoder *json.Encoder
+    nextID int
+    success bool
+    uri string
+    // The IDs of the AST nodes of the current feature.
+    nodeIDs map[interface{}]string
+    tree *Feature
+    // The step definitions already added, by location and pattern, and
+    // the hooks, by kind and location.
+    stepDefIDs map[string]string
+    hookIDs map[string]string
+    // The envelopes of the run: the sources, gherkin documents and
+    // pickles, the step definitions and hooks, the test cases, and what
+    // happened to them.
+    documents []message
+    glue []message
+    testCases []message
+    results []message
+    runStarted time.Time
+    // The steps and hooks of the current scenario, in order.
+    testSteps []*messageStep
+    // The AfterStep hooks of the next step, which is reported after them.
+    afterStep []*messageStep
+}
+
+type messageStep struct {
+    step *StepInfo
+    result *StepResult
+    // Set for a hook instead of step.
+    hook *HookInfo
+    // When the step or hook was reported.
+    reported time.Time
+    pickleStepID string
+}
+
+// When the step or hook started and finished; when it was reported for
+// a step that wasn't run.
+func (ts *messageStep) times() (time.Time, time.Time) {
+    if ts.result.Started.IsZero() {
+        return ts.reported, ts.reported
+    }
+    return ts.result.Started, ts.result.Started.Add(ts.result.Duration)
+}
+
+// The hook message type of each kind of hook.
+var messageHookTypes = map[string]string{
+    "Before": "BEFORE_TEST_CASE",
+    "After": "AFTER_TEST_CASE",
+    "BeforeStep": "BEFORE_TEST_STEP",
+    "AfterStep": "AFTER_TEST_STEP"}
+
+// An envelope or any object nested in one.
+type message map[string]interface{}
+
+// A formatter writing cucumber messages to w as newline-delimited JSON.
+func NewMessagesFormatter(w io.Writer) Formatter {
+    return &messagesFormatter{encoder: json.NewEncoder(w)}
+}
+
+func (m *messagesFormatter) id() string {
+    id := strconv.Itoa(m.nextID)
+    m.nextID++
+    return id
+}
+
+// Adds an envelope to the list, to be written when the run is finished.
+func (m *messagesFormatter) add(list *[]message, kind string, content message) {
+    *list = append(*list, message{kind: content})
+}
+
+func (m *messagesFormatter) write(kind string, content message) {
+    m.encoder.Encode(message{kind: content})
+}
+
+func messageTimestamp(t time.Time) message {
+    return message{"seconds": t.Unix(), "nanos": t.Nanosecond()}
+}
+
+func messageDuration(d time.Duration) message {
+    return message{"seconds": int64(d / time.Second), "nanos": int(d % time.Second)}
+}
+
+func messageLocation(loc Location) message {
+    return message{"line": loc.Line, "column": loc.Column}
+}
+
+// A source reference to a "file:line" location.
+func messageSourceReference(location string) message {
+    uri, line := location, 0
+    if i := strings.LastIndex(location, ":"); i >= 0 {
+        uri = location[:i]
+        line, _ = strconv.Atoi(location[i + 1:])
+    }
+    return message{"uri": uri, "location": message{"line": line}}
+}
+
+// Adds every step definition and hook, whether used or not.
+func (m *messagesFormatter) RunStarted(glue *Glue) {
+    m.nextID = 0
+    m.success = true
+    m.stepDefIDs, m.hookIDs = map[string]string{}, map[string]string{}
+    m.documents, m.glue, m.testCases, m.results = nil, nil, nil, nil
+    m.runStarted = time.Now()
+    m.write("meta", message{
+        "protocolVersion": "24.0.0",
+        "implementation": message{"name": "go-gherkin"},
+        "runtime": message{"name": "go", "version": runtime.Version()},
+        "os": message{"name": runtime.GOOS},
+        "cpu": message{"name": runtime.GOARCH}})
+    for _, stepDef := range glue.StepDefs {
+        m.stepDefID(stepDef)
+    }
+    for _, hook := range glue.Hooks {
+        m.hookID(hook)
+    }
+}
+
+func (m *messagesFormatter) FeatureStarted(feature *Feature, uri string) {
+    m.uri = uri
+    m.nodeIDs = map[interface{}]string{}
+    m.tree = feature
+    if uri != "" {
+        if data, err := ioutil.ReadFile(uri); err == nil {
+            m.add(&m.documents, "source", message{
+                "uri": uri,
+                "data": string(data),
+                "mediaType": "text/x.cucumber.gherkin+plain"})
+        }
+    }
+    comments := []message{}
+    for _, c := range feature.Comments {
+        comments = append(comments, message{"location": messageLocation(c.Location), "text": c.Text})
+    }
+    m.add(&m.documents, "gherkinDocument", message{
+        "uri": uri,
+        "feature": m.feature(feature),
+        "comments": comments})
+}
+
+func (m *messagesFormatter) feature(f *Feature) message {
+    children := []message{}
+    if f.Background != nil {
+        children = append(children, message{"background": m.background(f.Background)})
+    }
+    for _, def := range f.Scenarios {
+        children = append(children, message{"scenario": m.scenarioDefinition(def)})
+    }
+    for _, rule := range f.Rules {
+        children = append(children, message{"rule": m.rule(rule)})
+    }
+    return message{
+        "location": messageLocation(f.Location),
+        "tags": m.tagList(f.Tags),
+        "language": "en",
+        "keyword": f.Keyword,
+        "name": f.Name,
+        "description": f.Description,
+        "children": children}
+}
+
+func (m *messagesFormatter) rule(rule *Rule) message {
+    children := []message{}
+    if rule.Background != nil {
+        children = append(children, message{"background": m.background(rule.Background)})
+    }
+    for _, def := range rule.Scenarios {
+        children = append(children, message{"scenario": m.scenarioDefinition(def)})
+    }
+    return message{
+        "id": m.nodeID(rule),
+        "location": messageLocation(rule.Location),
+        "tags": m.tagList(rule.Tags),
+        "keyword": rule.Keyword,
+        "name": rule.Name,
+        "description": rule.Description,
+        "children": children}
+}
+
+func (m *messagesFormatter) background(bg *Background) message {
+    return message{
+        "id": m.nodeID(bg),
+        "location": messageLocation(bg.Location),
+        "keyword": bg.Keyword,
+        "name": bg.Name,
+        "description": bg.Description,
+        "steps": m.stepList(bg.Steps)}
+}
+
+// Scenarios and scenario outlines are both scenario messages; only
+// outlines have examples.
+func (m *messagesFormatter) scenarioDefinition(def ScenarioDefinition) message {
+    switch d := def.(type) {
+    case *Scenario:
+        return message{
+            "id": m.nodeID(d),
+            "location": messageLocation(d.Location),
+            "tags": m.tagList(d.Tags),
+            "keyword": d.Keyword,
+            "name": d.Name,
+            "description": d.Description,
+            "steps": m.stepList(d.Steps),
+            "examples": []message{}}
+    case *ScenarioOutline:
+        examples := []message{}
+        for _, e := range d.Examples {
+            examples = append(examples, m.examples(e))
+        }
+        return message{
+            "id": m.nodeID(d),
+            "location": messageLocation(d.Location),
+            "tags": m.tagList(d.Tags),
+            "keyword": d.Keyword,
+            "name": d.Name,
+            "description": d.Description,
+            "steps": m.stepList(d.Steps),
+            "examples": examples}
+    }
+    return message{}
+}
+
+func (m *messagesFormatter) examples(e *Examples) message {
+    body := []message{}
+    for _, row := range e.TableBody {
+        body = append(body, m.tableRow(row))
+    }
+    msg := message{
+        "id": m.nodeID(e),
+        "location": messageLocation(e.Location),
+        "tags": m.tagList(e.Tags),
+        "keyword": e.Keyword,
+        "name": e.Name,
+        "description": e.Description,
+        "tableBody": body}
+    if e.TableHeader != nil {
+        msg["tableHeader"] = m.tableRow(e.TableHeader)
+    }
+    return msg
+}
+
+func (m *messagesFormatter) stepList(steps []*Step) []message {
+    list := []message{}
+    for _, s := range steps {
+        msg := message{
+            "id": m.nodeID(s),
+            "location": messageLocation(s.Location),
+            "keyword": s.Keyword + " ",
+            "text": s.Text}
+        if doc := s.DocString; doc != nil {
+            msg["docString"] = message{
+                "location": messageLocation(doc.Location),
+                "content": doc.Content,
+                "delimiter": doc.Delimiter,
+                "mediaType": doc.MediaType}
+        }
+        if table := s.DataTable; table != nil {
+            rows := []message{}
+            for _, row := range table.Rows {
+                rows = append(rows, m.tableRow(row))
+            }
+            msg["dataTable"] = message{"location": messageLocation(table.Location), "rows": rows}
+        }
+        list = append(list, msg)
+    }
+    return list
+}
+
+func (m *messagesFormatter) tableRow(row *TableRow) message {
+    cells := []message{}
+    for _, c := range row.Cells {
+        cells = append(cells, message{"location": messageLocation(c.Location), "value": c.Value})
+    }
+    return message{"id": m.nodeID(row), "location": messageLocation(row.Location), "cells": cells}
+}
+
+func (m *messagesFormatter) tagList(tags []*Tag) []message {
+    list := []message{}
+    for _, tag := range tags {
+        list = append(list, message{"id": m.nodeID(tag), "location": messageLocation(tag.Location), "name": tag.Name})
+    }
+    return list
+}
+
+// The ID of the AST node, assigned when first asked for.
+func (m *messagesFormatter) nodeID(node interface{}) string {
+    if id, ok := m.nodeIDs[node]; ok {
+        return id
+    }
+    id := m.id()
+    m.nodeIDs[node] = id
+    return id
+}
+
+func (m *messagesFormatter) ScenarioStarted(scenario *ScenarioInfo) {
+    m.testSteps, m.afterStep = nil, nil
+}
+
+func (m *messagesFormatter) StepFinished(step *StepInfo, result *StepResult) {
+    m.testSteps = append(m.testSteps, &messageStep{step: step, result: result, reported: time.Now()})
+    m.testSteps = append(m.testSteps, m.afterStep...)
+    m.afterStep = nil
+}
+
+func (m *messagesFormatter) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    ts := &messageStep{hook: hook, result: result, reported: time.Now()}
+    if hook.Kind == "AfterStep" {
+        m.afterStep = append(m.afterStep, ts)
+    } else {
+        m.testSteps = append(m.testSteps, ts)
+    }
+}
+
+// Adds the pickle, the step definitions and hooks not added yet, the
+// test case and its results, now that the steps of the scenario are
+// known.
+func (m *messagesFormatter) ScenarioFinished(scenario *ScenarioInfo) {
+    pickleID := m.pickle(scenario)
+    testSteps := []message{}
+    stepIDs := []string{}
+    for _, ts := range m.testSteps {
+        id := m.id()
+        stepIDs = append(stepIDs, id)
+        if ts.step == nil {
+            testSteps = append(testSteps, message{"id": id, "hookId": m.hookID(ts.hook)})
+            continue
+        }
+        defIDs, args := []string{}, []message{}
+        for _, match := range ts.step.Matches {
+            defIDs = append(defIDs, m.stepDefID(match))
+            args = append(args, message{"stepMatchArguments": messageArguments(match)})
+        }
+        testSteps = append(testSteps, message{
+            "id": id,
+            "pickleStepId": ts.pickleStepID,
+            "stepDefinitionIds": defIDs,
+            "stepMatchArgumentsLists": args})
+    }
+    testCaseID := m.id()
+    m.add(&m.testCases, "testCase", message{"id": testCaseID, "pickleId": pickleID, "testSteps": testSteps})
+    startedID := m.id()
+    m.add(&m.results, "testCaseStarted", message{
+        "id": startedID,
+        "testCaseId": testCaseID,
+        "attempt": 0,
+        "timestamp": messageTimestamp(scenario.Started)})
+    for i, ts := range m.testSteps {
+        started, finished := ts.times()
+        m.add(&m.results, "testStepStarted", message{
+            "testCaseStartedId": startedID,
+            "testStepId": stepIDs[i],
+            "timestamp": messageTimestamp(started)})
+        result := message{
+            "status": strings.ToUpper(ts.result.Status.String()),
+            "duration": messageDuration(ts.result.Duration)}
+        if ts.result.Err != nil {
+            result["message"] = ts.result.Err.Error()
+        }
+        m.add(&m.results, "testStepFinished", message{
+            "testCaseStartedId": startedID,
+            "testStepId": stepIDs[i],
+            "testStepResult": result,
+            "timestamp": messageTimestamp(finished)})
+    }
+    m.add(&m.results, "testCaseFinished", message{
+        "testCaseStartedId": startedID,
+        "timestamp": messageTimestamp(scenario.Finished),
+        "willBeRetried": false})
+    switch scenario.Status {
+    case StatusFailed, StatusHookFailed, StatusAmbiguous:
+        m.success = false
+    }
+}
+
+// Adds the pickle of the scenario, the steps reported for it, and
+// returns its ID. Sets the pickleStepID of each test step.
+func (m *messagesFormatter) pickle(scenario *ScenarioInfo) string {
+    astNodeIDs := []string{m.nodeID(scenario.Definition)}
+    name := scenario.Name
+    if outline, ok := scenario.Definition.(*ScenarioOutline); ok {
+        astNodeIDs = append(astNodeIDs, m.nodeID(scenario.Row))
+        name = outline.Name
+    }
+    steps := []message{}
+    for _, ts := range m.testSteps {
+        if ts.step == nil {
+            continue
+        }
+        ts.pickleStepID = m.id()
+        stepNodeIDs := []string{}
+        if ts.step.Step != nil {
+            stepNodeIDs = append(stepNodeIDs, m.nodeID(ts.step.Step))
+            if ts.step.Background == nil && scenario.Row != nil {
+                stepNodeIDs = append(stepNodeIDs, m.nodeID(scenario.Row))
+            }
+        }
+        step := message{"id": ts.pickleStepID, "astNodeIds": stepNodeIDs, "text": ts.step.Text}
+        if doc := ts.step.DocString; doc != nil {
+            step["argument"] = message{"docString": message{"content": doc.Content, "mediaType": doc.MediaType}}
+        } else if len(ts.step.DataTable) > 0 {
+            rows := []message{}
+            for _, row := range ts.step.DataTable {
+                cells := []message{}
+                for _, value := range row {
+                    cells = append(cells, message{"value": value})
+                }
+                rows = append(rows, message{"cells": cells})
+            }
+            step["argument"] = message{"dataTable": message{"rows": rows}}
+        }
+        steps = append(steps, step)
+    }
+    tags := []message{}
+    for _, name := range scenario.Tags {
+        tag := message{"name": name}
+        if t := declaredTag(m.tree, scenario, name); t != nil {
+            tag["astNodeId"] = m.nodeID(t)
+        }
+        tags = append(tags, tag)
+    }
+    id := m.id()
+    m.add(&m.documents, "pickle", message{
+        "id": id,
+        "uri": m.uri,
+        "name": name,
+        "language": "en",
+        "steps": steps,
+        "tags": tags,
+        "astNodeIds": astNodeIDs})
+    return id
+}
+
+func messageArguments(match StepMatch) []message {
+    args := []message{}
+    for _, arg := range match.Arguments {
+        group := message{"children": []message{}}
+        if arg.Start >= 0 {
+            group["start"] = arg.Start
+            group["value"] = arg.Value
+        }
+        args = append(args, message{"group": group})
+    }
+    return args
+}
+
+// The ID of the step definition, adding it when first used.
+func (m *messagesFormatter) stepDefID(match StepMatch) string {
+    key := match.Location + " " + match.Pattern
+    if id, ok := m.stepDefIDs[key]; ok {
+        return id
+    }
+    id := m.id()
+    m.stepDefIDs[key] = id
+    patternType := "REGULAR_EXPRESSION"
+    if match.Expression {
+        patternType = "CUCUMBER_EXPRESSION"
+    }
+    m.add(&m.glue, "stepDefinition", message{
+        "id": id,
+        "pattern": message{"source": match.Pattern, "type": patternType},
+        "sourceReference": messageSourceReference(match.Location)})
+    return id
+}
+
+// The ID of the hook, adding it when first used.
+func (m *messagesFormatter) hookID(hook *HookInfo) string {
+    key := hook.Kind + " " + hook.Location
+    if id, ok := m.hookIDs[key]; ok {
+        return id
+    }
+    id := m.id()
+    m.hookIDs[key] = id
+    m.add(&m.glue, "hook", message{
+        "id": id,
+        "type": messageHookTypes[hook.Kind],
+        "sourceReference": messageSourceReference(hook.Location)})
+    return id
+}
+
+func (m *messagesFormatter) FeatureFinished(feature *Feature) {}
+
+func (m *messagesFormatter) RunFinished(rpt Report) {
+    for _, list := range [][]message{m.documents, m.glue} {
+        for _, envelope := range list {
+            m.encoder.Encode(envelope)
+        }
+    }
+    m.write("testRunStarted", message{"timestamp": messageTimestamp(m.runStarted)})
+    for _, list := range [][]message{m.testCases, m.results} {
+        for _, envelope := range list {
+            m.encoder.Encode(envelope)
+        }
+    }
+    m.write("testRunFinished", message{
+        "success": m.success && rpt.failedSteps == 0 && rpt.failedHooks == 0,
+        "timestamp": messageTimestamp(time.Now())})
+}
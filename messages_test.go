@@ -0,0 +1,246 @@
+package gherkin
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+// Decodes each line into a single-key envelope.
+func readMessages(t *testing.T, out []byte) ([]string, []map[string]interface{}) {
+    kinds, contents := []string{}, []map[string]interface{}{}
+    scanner := bufio.NewScanner(bytes.NewReader(out))
+    scanner.Buffer(nil, 1024 * 1024)
+    for scanner.Scan() {
+        envelope := map[string]map[string]interface{}{}
+        AssertThat(t, json.Unmarshal(scanner.Bytes(), &envelope), Equals(nil))
+        AssertThat(t, len(envelope), Equals(1))
+        for kind, content := range envelope {
+            kinds = append(kinds, kind)
+            contents = append(contents, content)
+        }
+    }
+    return kinds, contents
+}
+
+func TestMessagesFormatterWritesEnvelopesInOrder(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef(`^I have (\d+) apples$`, func(w *World, ctx *Context, n int) { })
+    g.RegisterStepDef("^fail$", func(w *World, ctx *Context) { w.Errorf("broken") })
+    g.AddFormatter(NewMessagesFormatter(&buf))
+    dir, _ := ioutil.TempDir("", "gherkin")
+    defer os.RemoveAll(dir)
+    filename := filepath.Join(dir, "apples.feature")
+    ioutil.WriteFile(filename, []byte("Feature: Apples\n  @fruit\n  Scenario: Counting\n    Given I have 3 apples\n    Then fail\n"), 0644)
+
+    g.RunFeature(&recordingErrorable{}, &Context{}, filename)
+
+    kinds, contents := readMessages(t, buf.Bytes())
+    AssertThat(t, kinds, Equals([]string{
+        "meta", "source", "gherkinDocument", "pickle",
+        "stepDefinition", "stepDefinition", "testRunStarted", "testCase", "testCaseStarted",
+        "testStepStarted", "testStepFinished", "testStepStarted", "testStepFinished",
+        "testCaseFinished", "testRunFinished"}))
+    AssertThat(t, contents[1]["uri"], Equals(filename))
+    AssertThat(t, contents[2]["uri"], Equals(filename))
+
+    pickle := contents[3]
+    AssertThat(t, pickle["name"], Equals("Counting"))
+    AssertThat(t, fmt.Sprint(pickle["tags"]), Equals("[map[astNodeId:3 name:@fruit]]"))
+    stepDef := contents[4]
+    AssertThat(t, fmt.Sprint(stepDef["pattern"]), Equals(`map[source:^I have (\d+) apples$ type:REGULAR_EXPRESSION]`))
+
+    testCase := contents[7]
+    AssertThat(t, testCase["pickleId"], Equals(pickle["id"]))
+    testStep := testCase["testSteps"].([]interface{})[0].(map[string]interface{})
+    AssertThat(t, fmt.Sprint(testStep["stepDefinitionIds"]), Equals(fmt.Sprintf("[%s]", stepDef["id"])))
+    AssertThat(t, fmt.Sprint(testStep["stepMatchArgumentsLists"]),
+        Equals("[map[stepMatchArguments:[map[group:map[children:[] start:7 value:3]]]]]"))
+
+    AssertThat(t, contents[8]["testCaseId"], Equals(testCase["id"]))
+    passed := contents[10]["testStepResult"].(map[string]interface{})
+    AssertThat(t, passed["status"], Equals("PASSED"))
+    AssertThat(t, passed["message"], Equals(nil))
+    failed := contents[12]["testStepResult"].(map[string]interface{})
+    AssertThat(t, failed["status"], Equals("FAILED"))
+    AssertThat(t, failed["message"], Equals("broken"))
+    AssertThat(t, contents[14]["success"], Equals(false))
+}
+
+func TestMessagesFormatterIDsAreStable(t *testing.T) {
+    run := func() string {
+        var buf bytes.Buffer
+        g := createWriterlessRunner()
+        g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+        g.AddFormatter(NewMessagesFormatter(&buf))
+        g.Execute(`Feature:
+  Background:
+    Given a background step
+  Scenario Outline: Rows
+    Given <a>
+    Examples:
+      | a |
+      | x |
+      | y |
+`, &Context{})
+        ids := ""
+        kinds, contents := readMessages(t, buf.Bytes())
+        for i, kind := range kinds {
+            if id, ok := contents[i]["id"]; ok {
+                ids += fmt.Sprintf("%s=%s ", kind, id)
+            }
+        }
+        return ids
+    }
+
+    first := run()
+    AssertThat(t, first, Equals(run()))
+    AssertThat(t, first, Equals("pickle=11 pickle=18 stepDefinition=0 " +
+        "testCase=14 testCase=21 testCaseStarted=15 testCaseStarted=22 "))
+}
+
+// The seconds and nanoseconds of a timestamp message.
+func messageTime(content interface{}) time.Time {
+    ts := content.(map[string]interface{})
+    return time.Unix(int64(ts["seconds"].(float64)), int64(ts["nanos"].(float64)))
+}
+
+func TestMessagesFormatterWritesHooksAndMeasuredTimes(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { time.Sleep(10 * time.Millisecond) })
+    g.Before("", func(info *ScenarioInfo, ctx *Context) { })
+    g.AfterStep("", func(info *StepInfo, ctx *Context) { })
+    g.AddFormatter(NewMessagesFormatter(&buf))
+
+    g.Execute(`Feature:
+  Scenario:
+    Given a slow step
+`, &Context{})
+
+    kinds, contents := readMessages(t, buf.Bytes())
+    AssertThat(t, kinds, Equals([]string{
+        "meta", "gherkinDocument", "pickle", "stepDefinition", "hook", "hook", "testRunStarted",
+        "testCase", "testCaseStarted", "testStepStarted", "testStepFinished", "testStepStarted",
+        "testStepFinished", "testStepStarted", "testStepFinished", "testCaseFinished", "testRunFinished"}))
+    AssertThat(t, contents[4]["type"], Equals("BEFORE_TEST_CASE"))
+    AssertThat(t, contents[5]["type"], Equals("AFTER_TEST_STEP"))
+    testSteps := contents[7]["testSteps"].([]interface{})
+    AssertThat(t, testSteps[0].(map[string]interface{})["hookId"], Equals(contents[4]["id"]))
+    AssertThat(t, testSteps[1].(map[string]interface{})["pickleStepId"] == nil, IsFalse)
+    AssertThat(t, testSteps[2].(map[string]interface{})["hookId"], Equals(contents[5]["id"]))
+
+    stepStarted, stepFinished := messageTime(contents[11]["timestamp"]), messageTime(contents[12]["timestamp"])
+    AssertThat(t, stepFinished.Sub(stepStarted) >= 10 * time.Millisecond, IsTrue)
+    AssertThat(t, messageTime(contents[8]["timestamp"]).After(stepStarted), IsFalse)
+    AssertThat(t, messageTime(contents[15]["timestamp"]).Before(stepFinished), IsFalse)
+}
+
+func TestMessagesFormatterWritesEveryStepDefinitionAndHook(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^used$", func(w *World, ctx *Context) { })
+    g.RegisterStepDef("^unused$", func(w *World, ctx *Context) { })
+    hook := func(info *ScenarioInfo, ctx *Context) { }
+    g.Before("", hook)
+    g.After("", hook)
+    g.AddFormatter(NewMessagesFormatter(&buf))
+
+    g.Execute(`Feature:
+  Scenario:
+    Given used
+`, &Context{})
+
+    kinds, contents := readMessages(t, buf.Bytes())
+    glue := map[string]string{}
+    for i, kind := range kinds {
+        switch kind {
+        case "stepDefinition":
+            source := contents[i]["pattern"].(map[string]interface{})["source"].(string)
+            glue[source] = contents[i]["id"].(string)
+        case "hook":
+            glue[contents[i]["type"].(string)] = contents[i]["id"].(string)
+        case "testCase":
+            hookIDs := []interface{}{}
+            for _, ts := range contents[i]["testSteps"].([]interface{}) {
+                if id, ok := ts.(map[string]interface{})["hookId"]; ok {
+                    hookIDs = append(hookIDs, id)
+                }
+            }
+            AssertThat(t, hookIDs, Equals([]interface{}{glue["BEFORE_TEST_CASE"], glue["AFTER_TEST_CASE"]}))
+        }
+    }
+    AssertThat(t, len(glue), Equals(4))
+    AssertThat(t, glue["^unused$"] == "", IsFalse)
+}
+
+func TestMessagesFormatterPointsPickleTagsAtTheirScenario(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef(".", func(w *World, ctx *Context) { })
+    g.AddFormatter(NewMessagesFormatter(&buf))
+    g.Execute(`Feature:
+  @smoke
+  Scenario: A
+    Given a step
+  @smoke
+  Scenario: B
+    Given a step
+`, &Context{})
+
+    kinds, contents := readMessages(t, buf.Bytes())
+    scenarios := contents[1]["feature"].(map[string]interface{})["children"].([]interface{})
+    tagIDs := []interface{}{}
+    for _, child := range scenarios {
+        scen := child.(map[string]interface{})["scenario"].(map[string]interface{})
+        tagIDs = append(tagIDs, scen["tags"].([]interface{})[0].(map[string]interface{})["id"])
+    }
+    pickleTagIDs := []interface{}{}
+    for i, kind := range kinds {
+        if kind == "pickle" {
+            pickleTagIDs = append(pickleTagIDs, contents[i]["tags"].([]interface{})[0].(map[string]interface{})["astNodeId"])
+        }
+    }
+    AssertThat(t, pickleTagIDs, Equals(tagIDs))
+}
+
+func TestMessagesFormatterTimesConcurrentScenariosWhenTheyRun(t *testing.T) {
+    var buf bytes.Buffer
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^slow$", func(w *World, ctx *Context) { time.Sleep(20 * time.Millisecond) })
+    g.RegisterStepDef("^fast$", func(w *World, ctx *Context) { })
+    g.SetConcurrency(2)
+    g.SetContextFactory(func() interface{} { return &Context{} })
+    g.AddFormatter(NewMessagesFormatter(&buf))
+
+    g.Execute(`Feature:
+  Scenario: slow
+    Given slow
+  Scenario: fast
+    Given fast
+`, &Context{})
+
+    kinds, contents := readMessages(t, buf.Bytes())
+    var caseStarted, caseFinished time.Time
+    var stepTimes []time.Time
+    for i, kind := range kinds {
+        switch kind {
+        case "testCaseStarted":
+            caseStarted, stepTimes = messageTime(contents[i]["timestamp"]), nil
+        case "testStepStarted", "testStepFinished":
+            stepTimes = append(stepTimes, messageTime(contents[i]["timestamp"]))
+        case "testCaseFinished":
+            caseFinished = messageTime(contents[i]["timestamp"])
+            AssertThat(t, caseStarted.After(stepTimes[0]), IsFalse)
+            AssertThat(t, caseFinished.Before(stepTimes[len(stepTimes) - 1]), IsFalse)
+        }
+    }
+}
@@ -0,0 +1,259 @@
+package gherkin
+
+import (
+    "encoding"
+    "fmt"
+    "reflect"
+    re "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// A parameter type usable as {name} in a Cucumber Expression. The
+// regexp must not contain capture groups. If set, transform is applied to
+// the matched text before it is converted to the step function's
+// argument type. If set, transformer converts the matched text into
+// step arguments of type out.
+type parameterType struct {
+    name string
+    regexp string
+    transform func(string) string
+    transformer reflect.Value
+    out reflect.Type
+}
+
+// The parameter types known to a Runner, by the name used in Cucumber
+// Expressions, and those whose transformer also converts the captures
+// of regular expressions, by the Go type they return.
+type parameterTypes struct {
+    byName map[string]*parameterType
+    transformers map[reflect.Type]*parameterType
+}
+
+var (
+    errorType = reflect.TypeOf((*error)(nil)).Elem()
+    textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+    durationType = reflect.TypeOf(time.Duration(0))
+    timeType = reflect.TypeOf(time.Time{})
+)
+
+// Layouts tried in order when converting to time.Time.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+func defaultParameterTypes() *parameterTypes {
+    types := &parameterTypes{
+        byName: map[string]*parameterType{},
+        transformers: map[reflect.Type]*parameterType{}}
+    for _, pt := range []*parameterType{
+        {name: "int", regexp: `-?\d+`},
+        {name: "float", regexp: `[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`},
+        {name: "word", regexp: `[^\s]+`},
+        {name: "string", regexp: `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`, transform: unquote},
+        {name: "", regexp: `.*`},
+    } {
+        types.byName[pt.name] = pt
+    }
+    return types
+}
+
+// Strips the quotes of a {string} match and unescapes the quote
+// character within it.
+func unquote(s string) string {
+    quote := s[:1]
+    return strings.Replace(s[1:len(s) - 1], `\` + quote, quote, -1)
+}
+
+// Adds the parameter type {name} matching regexp. The transformer is
+// either nil or a func(string) T or func(string) (T, error), which then
+// converts the matches of {name} into step arguments of type T. Unless T
+// is a type converted without a transformer, such as string or int, it
+// also converts the captures of regular expressions for arguments of
+// type T, so only one parameter type may transform into T.
+func (pts *parameterTypes) register(name, regexp string, transformer interface{}) error {
+    if name == "" {
+        return fmt.Errorf("parameter type must have a name")
+    }
+    if _, ok := pts.byName[name]; ok {
+        return fmt.Errorf("parameter type {%s} is already defined", name)
+    }
+    if _, err := re.Compile(regexp); err != nil {
+        return fmt.Errorf("parameter type {%s}: %s", name, err)
+    }
+    pt := &parameterType{name: name, regexp: nonCapturing(regexp)}
+    if transformer != nil {
+        t := reflect.TypeOf(transformer)
+        if t.Kind() != reflect.Func || t.NumIn() != 1 || t.In(0).Kind() != reflect.String ||
+            t.NumOut() < 1 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+            return fmt.Errorf("parameter type {%s}: transformer must be func(string) T or func(string) (T, error), not %s", name, t)
+        }
+        pt.transformer, pt.out = reflect.ValueOf(transformer), t.Out(0)
+        if !(*parameterTypes)(nil).canConvert(pt.out) {
+            if other, ok := pts.transformers[pt.out]; ok {
+                return fmt.Errorf("parameter type {%s}: {%s} already transforms into %s", name, other.name, pt.out)
+            }
+            pts.transformers[pt.out] = pt
+        }
+    }
+    pts.byName[name] = pt
+    return nil
+}
+
+// Converts the text matched by the parameter type into an argument of
+// type t, with the transformer of the parameter type if it returns t.
+func (pts *parameterTypes) convertParam(pt *parameterType, s string, t reflect.Type) (reflect.Value, error) {
+    if pt != nil && pt.transformer.IsValid() && pt.out == t {
+        return pt.apply(s)
+    }
+    return pts.convert(s, t)
+}
+
+func (pt *parameterType) apply(s string) (reflect.Value, error) {
+    out := pt.transformer.Call([]reflect.Value{reflect.ValueOf(s)})
+    if len(out) == 2 && !out[1].IsNil() {
+        return reflect.Value{}, out[1].Interface().(error)
+    }
+    return out[0], nil
+}
+
+// Rewrites every capture group of pattern, named ones included, into a
+// non-capturing one, so that a parameter type adds exactly one group to
+// an expression.
+func nonCapturing(pattern string) string {
+    out := []rune{}
+    runes := []rune(pattern)
+    inClass := false
+    for i := 0; i < len(runes); i++ {
+        c := runes[i]
+        out = append(out, c)
+        switch {
+        case c == '\\' && i + 1 < len(runes):
+            i++
+            out = append(out, runes[i])
+        case c == '[':
+            inClass = true
+        case c == ']':
+            inClass = false
+        case c == '(' && !inClass && (i + 1 == len(runes) || runes[i + 1] != '?'):
+            out = append(out, '?', ':')
+        case c == '(' && !inClass:
+            // (?P<name> and (?<name> become (?:
+            rest := string(runes[i + 1:])
+            for _, prefix := range []string{"?P<", "?<"} {
+                if end := strings.Index(rest, ">"); strings.HasPrefix(rest, prefix) && end > 0 {
+                    out = append(out, '?', ':')
+                    i += end + 1
+                    break
+                }
+            }
+        }
+    }
+    return string(out)
+}
+
+// Whether a capture can be converted into an argument of type t.
+func (pts *parameterTypes) canConvert(t reflect.Type) bool {
+    if pts != nil {
+        if _, ok := pts.transformers[t]; ok {
+            return true
+        }
+    }
+    if t == durationType || t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+        return true
+    }
+    switch t.Kind() {
+    case reflect.Ptr, reflect.Slice:
+        return pts.canConvert(t.Elem())
+    case reflect.Bool, reflect.String,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64:
+        return true
+    }
+    return false
+}
+
+// Converts a capture into an argument of type t. Slices are read as
+// comma separated lists, except []byte which receives the raw text.
+func (pts *parameterTypes) convert(s string, t reflect.Type) (reflect.Value, error) {
+    if pts != nil {
+        if pt, ok := pts.transformers[t]; ok {
+            return pt.apply(s)
+        }
+    }
+    v := reflect.New(t).Elem()
+    var err error
+    switch {
+    case t == durationType:
+        var d time.Duration
+        d, err = time.ParseDuration(s)
+        v.SetInt(int64(d))
+        return v, err
+    case t == timeType:
+        return convertTime(s)
+    case reflect.PtrTo(t).Implements(textUnmarshalerType):
+        err = v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+        return v, err
+    }
+    switch t.Kind() {
+    case reflect.Ptr:
+        var elem reflect.Value
+        if elem, err = pts.convert(s, t.Elem()); err == nil {
+            v = reflect.New(t.Elem())
+            v.Elem().Set(elem)
+        }
+    case reflect.Slice:
+        if t.Elem().Kind() == reflect.Uint8 {
+            v.SetBytes([]byte(s))
+            break
+        }
+        v, err = pts.convertList(s, t)
+    case reflect.Bool:
+        var b bool
+        b, err = strconv.ParseBool(s)
+        v.SetBool(b)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        var n int64
+        n, err = strconv.ParseInt(s, 10, t.Bits())
+        v.SetInt(n)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        var n uint64
+        n, err = strconv.ParseUint(s, 10, t.Bits())
+        v.SetUint(n)
+    case reflect.Float32, reflect.Float64:
+        var f float64
+        f, err = strconv.ParseFloat(s, t.Bits())
+        v.SetFloat(f)
+    case reflect.String:
+        v.SetString(s)
+    default:
+        err = fmt.Errorf("type %s is not supported", t)
+    }
+    return v, err
+}
+
+func (pts *parameterTypes) convertList(s string, t reflect.Type) (reflect.Value, error) {
+    v := reflect.MakeSlice(t, 0, 0)
+    if strings.TrimSpace(s) == "" {
+        return v, nil
+    }
+    for _, item := range strings.Split(s, ",") {
+        elem, err := pts.convert(strings.TrimSpace(item), t.Elem())
+        if err != nil {
+            return v, err
+        }
+        v = reflect.Append(v, elem)
+    }
+    return v, nil
+}
+
+func convertTime(s string) (reflect.Value, error) {
+    var err error
+    for _, layout := range timeLayouts {
+        var tm time.Time
+        if tm, err = time.Parse(layout, s); err == nil {
+            return reflect.ValueOf(tm), nil
+        }
+    }
+    return reflect.ValueOf(time.Time{}), err
+}
@@ -0,0 +1,163 @@
+package gherkin
+
+import (
+    "fmt"
+    "net"
+    "reflect"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+type Money struct {
+    Cents int
+    Currency string
+}
+
+type Color string
+
+func parseMoney(s string) (Money, error) {
+    parts := strings.Fields(s)
+    if len(parts) != 2 {
+        return Money{}, fmt.Errorf("bad money %q", s)
+    }
+    n, err := strconv.ParseFloat(parts[0], 64)
+    return Money{int(n * 100), parts[1]}, err
+}
+
+func convertCapture(t *testing.T, s string, v interface{}) interface{} {
+    val, err := defaultParameterTypes().convert(s, reflect.TypeOf(v))
+    AssertThat(t, err, Equals(nil))
+    return val.Interface()
+}
+
+func TestConvertsUnsignedIntegers(t *testing.T) {
+    AssertThat(t, convertCapture(t, "255", uint8(0)), Equals(uint8(255)))
+    AssertThat(t, convertCapture(t, "42", uint(0)), Equals(uint(42)))
+}
+
+func TestConvertsDurationsAndTimes(t *testing.T) {
+    AssertThat(t, convertCapture(t, "1m30s", time.Duration(0)), Equals(90 * time.Second))
+    AssertThat(t, convertCapture(t, "2013-05-01", time.Time{}), Equals(time.Date(2013, 5, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestConvertsTextUnmarshalers(t *testing.T) {
+    AssertThat(t, convertCapture(t, "10.0.0.1", net.IP{}).(net.IP).String(), Equals("10.0.0.1"))
+}
+
+func TestConvertsPointersAndSlices(t *testing.T) {
+    AssertThat(t, *(convertCapture(t, "7", new(int)).(*int)), Equals(7))
+    AssertThat(t, convertCapture(t, "1, 2,3", []int{}), Equals([]int{1, 2, 3}))
+    AssertThat(t, convertCapture(t, "red,blue", []Color{}), Equals([]Color{"red", "blue"}))
+}
+
+func TestDoesNotConvertUnsupportedTypes(t *testing.T) {
+    pts := defaultParameterTypes()
+    AssertThat(t, pts.canConvert(reflect.TypeOf(map[string]int{})), IsFalse)
+    AssertThat(t, pts.canConvert(reflect.TypeOf([]*time.Duration{})), IsTrue)
+}
+
+func TestRejectsInvalidParameterTypes(t *testing.T) {
+    g := createWriterlessRunner()
+    AssertThat(t, g.RegisterParameterType("int", `\d+`, nil) == nil, IsFalse)
+    AssertThat(t, g.RegisterParameterType("money", `(`, parseMoney) == nil, IsFalse)
+    AssertThat(t, g.RegisterParameterType("money", `.*`, func(n int) Money { return Money{} }) == nil, IsFalse)
+}
+
+func TestCustomParameterTypeInExpression(t *testing.T) {
+    var paid Money
+    g := createWriterlessRunner()
+    err := g.RegisterParameterType("money", `(\d+(?:\.\d+)?) (EUR|USD)`, parseMoney)
+    AssertThat(t, err, Equals(nil))
+    err = g.RegisterStepDef("I pay {money} to {word}", func(w *World, ctx *Context, m Money, who string) {
+        paid = m
+    })
+    AssertThat(t, err, Equals(nil))
+
+    g.Execute(`Feature:
+        Scenario:
+            When I pay 12.50 EUR to Bob
+    `, &Context{})
+
+    AssertThat(t, paid, Equals(Money{1250, "EUR"}))
+}
+
+func TestCustomParameterTypeTransformsRegexCaptures(t *testing.T) {
+    var paid Money
+    g := createWriterlessRunner()
+    g.RegisterParameterType("money", `.*`, parseMoney)
+    g.RegisterStepDef("^I pay (.*)$", func(w *World, ctx *Context, m Money) { paid = m })
+
+    g.Execute(`Feature:
+        Scenario:
+            When I pay 3 USD
+    `, &Context{})
+
+    AssertThat(t, paid, Equals(Money{300, "USD"}))
+}
+
+func TestMakesParameterTypeGroupsNonCapturing(t *testing.T) {
+    AssertThat(t, nonCapturing(`(\d+) (?:a|b) [(]x\(`), Equals(`(?:\d+) (?:a|b) [(]x\(`))
+}
+
+func TestBuiltinTypeTransformerOnlyAppliesToItsParameterType(t *testing.T) {
+    shouted, named := "", ""
+    g := createWriterlessRunner()
+    err := g.RegisterParameterType("shout", `[a-z]+`, func(s string) string { return strings.ToUpper(s) + "!" })
+    AssertThat(t, err, Equals(nil))
+    g.RegisterStepDef("I say {shout}", func(w *World, ctx *Context, s string) { shouted = s })
+    g.RegisterStepDef(`^my name is (\w+)$`, func(w *World, ctx *Context, s string) { named = s })
+
+    g.Execute(`Feature:
+        Scenario:
+            Given I say hello
+            And my name is bob
+    `, &Context{})
+
+    AssertThat(t, shouted, Equals("HELLO!"))
+    AssertThat(t, named, Equals("bob"))
+}
+
+func TestParameterTypesWithTheSameBuiltinTypeKeepTheirOwnTransformers(t *testing.T) {
+    got := []string{}
+    g := createWriterlessRunner()
+    g.RegisterParameterType("upper", `[a-z]+`, func(s string) string { return strings.ToUpper(s) })
+    g.RegisterParameterType("reversed", `[a-z]+`, func(s string) string { return s[1:] + s[:1] })
+    g.RegisterStepDef("{upper} and {reversed}", func(w *World, ctx *Context, a, b string) { got = []string{a, b} })
+
+    g.Execute(`Feature:
+        Scenario:
+            Given abc and abc
+    `, &Context{})
+
+    AssertThat(t, got, Equals([]string{"ABC", "bca"}))
+}
+
+func TestRejectsSecondTransformerIntoTheSameCustomType(t *testing.T) {
+    g := createWriterlessRunner()
+    AssertThat(t, g.RegisterParameterType("money", `.*`, parseMoney), Equals(nil))
+    err := g.RegisterParameterType("cash", `.*`, parseMoney)
+    AssertThat(t, err == nil, IsFalse)
+    AssertThat(t, strings.Contains(err.Error(), "{money} already transforms into"), IsTrue)
+}
+
+func TestMakesNamedGroupsNonCapturing(t *testing.T) {
+    AssertThat(t, nonCapturing(`(?P<a>\d+)-(?<b>\d+)`), Equals(`(?:\d+)-(?:\d+)`))
+
+    got := []int{}
+    g := createWriterlessRunner()
+    AssertThat(t, g.RegisterParameterType("pair", `(?P<a>\d+)-(\d+)`, nil), Equals(nil))
+    err := g.RegisterStepDef("I have {pair} and {int}", func(w *World, ctx *Context, pair string, n int) {
+        got = append(got, len(pair), n)
+    })
+    AssertThat(t, err, Equals(nil))
+
+    g.Execute(`Feature:
+        Scenario:
+            Given I have 12-3 and 4
+    `, &Context{})
+
+    AssertThat(t, got, Equals([]int{4, 4}))
+}
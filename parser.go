@@ -0,0 +1,439 @@
+package gherkin
+
+import (
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    re "regexp"
+    "strings"
+    "unicode"
+)
+
+var (
+    featureLine = re.MustCompile(`^(Feature|Business Need|Ability):\s*(.*?)\s*$`)
+    backgroundLine = re.MustCompile(`^(Background):\s*(.*?)\s*$`)
+    ruleLine = re.MustCompile(`^(Rule):\s*(.*?)\s*$`)
+    outlineLine = re.MustCompile(`^(Scenario Outline|Scenario Template):\s*(.*?)\s*$`)
+    scenarioLine = re.MustCompile(`^(Scenario|Example):\s*(.*?)\s*$`)
+    examplesLine = re.MustCompile(`^(Examples|Scenarios):\s*(.*?)\s*$`)
+    stepLine = re.MustCompile(`^(Given|When|Then|And|But|\*)\s+(.*?)\s*$`)
+    tagToken = re.MustCompile(`@[^\s@]+`)
+)
+
+// A problem found while parsing a feature. File is empty when the
+// feature was not read from a file.
+type ParseError struct {
+    File string
+    Location
+    Message string
+}
+
+func (e *ParseError) Error() string {
+    if e.File == "" {
+        return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+    }
+    return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// All problems found while parsing a feature, in document order.
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+    msgs := make([]string, len(errs))
+    for i, e := range errs {
+        msgs[i] = e.Error()
+    }
+    return strings.Join(msgs, "\n")
+}
+
+// Parse a single Gherkin feature. Lines that are neither keywords,
+// steps, tables, doc strings, tags nor comments are kept as the
+// description of the preceding header, or ignored if there is none.
+// Parsing continues past problems, which are returned together as
+// ParseErrors.
+func ParseFeature(r io.Reader) (*Feature, error) {
+    return parseFeature(r, "")
+}
+
+// Parse the feature stored in filename. Any ParseErrors refer to it.
+func ParseFeatureFile(filename string) (*Feature, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return parseFeature(file, filename)
+}
+
+func parseFeature(r io.Reader, filename string) (*Feature, error) {
+    data, err := ioutil.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    p := &parser{file: filename, lines: strings.Split(string(data), "\n")}
+    return p.parse()
+}
+
+type parser struct {
+    file string
+    errors ParseErrors
+    lines []string
+    lineNo int
+    feature *Feature
+    comments []*Comment
+    tags []*Tag
+    rule *Rule
+    outline *ScenarioOutline
+    examples *Examples
+    steps *[]*Step
+    step *Step
+    description *string
+    descLines []string
+}
+
+func (p *parser) parse() (*Feature, error) {
+    for p.lineNo = 0; p.lineNo < len(p.lines); p.lineNo++ {
+        p.parseLine()
+    }
+    p.closeDescription()
+    if len(p.errors) > 0 {
+        return nil, p.errors
+    }
+    if p.feature == nil {
+        p.feature = &Feature{}
+    }
+    p.feature.Comments = p.comments
+    return p.feature, nil
+}
+
+func (p *parser) errorf(loc Location, format string, args ...interface{}) {
+    p.errors = append(p.errors, &ParseError{p.file, loc, fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) parseLine() {
+    line := strings.TrimRight(p.lines[p.lineNo], " \t\r")
+    trimmed := strings.TrimSpace(line)
+    loc := Location{Line: p.lineNo + 1, Column: indentOf(line) + 1}
+
+    if trimmed == "" {
+        if p.description != nil {
+            p.descLines = append(p.descLines, "")
+        }
+        return
+    }
+    if strings.HasPrefix(trimmed, "#") {
+        p.comments = append(p.comments, &Comment{loc, trimmed})
+        return
+    }
+    if strings.HasPrefix(trimmed, "@") {
+        p.closeDescription()
+        p.tags = append(p.tags, parseTags(line, loc.Line)...)
+        return
+    }
+    if m := featureLine.FindStringSubmatch(trimmed); m != nil {
+        p.startFeature(loc, m[1], m[2])
+        return
+    }
+    if m := backgroundLine.FindStringSubmatch(trimmed); m != nil {
+        p.startBackground(loc, m[1], m[2])
+        return
+    }
+    if m := ruleLine.FindStringSubmatch(trimmed); m != nil {
+        p.startRule(loc, m[1], m[2])
+        return
+    }
+    if m := outlineLine.FindStringSubmatch(trimmed); m != nil {
+        p.startScenarioOutline(loc, m[1], m[2])
+        return
+    }
+    if m := scenarioLine.FindStringSubmatch(trimmed); m != nil {
+        p.startScenario(loc, m[1], m[2])
+        return
+    }
+    if m := examplesLine.FindStringSubmatch(trimmed); m != nil {
+        p.startExamples(loc, m[1], m[2])
+        return
+    }
+    if m := stepLine.FindStringSubmatch(trimmed); m != nil {
+        p.addStep(loc, m[1], m[2])
+        return
+    }
+    if strings.HasPrefix(trimmed, "|") {
+        p.addTableRow(line, loc)
+        return
+    }
+    if strings.HasPrefix(trimmed, `"""`) || strings.HasPrefix(trimmed, "```") {
+        p.readDocString(loc, trimmed[:3], strings.TrimSpace(trimmed[3:]))
+        return
+    }
+    if p.description != nil {
+        p.descLines = append(p.descLines, trimmed)
+    }
+}
+
+func (p *parser) openDescription(desc *string) {
+    p.description = desc
+    p.descLines = nil
+}
+
+func (p *parser) closeDescription() {
+    if p.description != nil {
+        *p.description = strings.Trim(strings.Join(p.descLines, "\n"), "\n")
+    }
+    p.description = nil
+    p.descLines = nil
+}
+
+func (p *parser) takeTags() []*Tag {
+    tags := p.tags
+    p.tags = nil
+    return tags
+}
+
+// Prepares for a new header line. A header before the Feature: line is
+// reported, and parsing continues with an implicit feature.
+func (p *parser) startHeader(loc Location, keyword string) {
+    p.closeDescription()
+    if p.feature == nil {
+        p.errorf(loc, "expected Feature: before %s:", keyword)
+        p.feature = &Feature{Location: loc}
+    }
+    p.outline = nil
+    p.examples = nil
+    p.steps = nil
+    p.step = nil
+}
+
+func (p *parser) startFeature(loc Location, keyword, name string) {
+    if p.feature != nil {
+        p.errorf(loc, "unexpected %s:, a file may only contain one feature", keyword)
+        return
+    }
+    p.feature = &Feature{Location: loc}
+    p.startHeader(loc, keyword)
+    p.feature.Tags = p.takeTags()
+    p.feature.Keyword = keyword
+    p.feature.Name = name
+    p.openDescription(&p.feature.Description)
+}
+
+func (p *parser) startBackground(loc Location, keyword, name string) {
+    p.startHeader(loc, keyword)
+    if len(p.tags) > 0 {
+        p.errorf(p.tags[0].Location, "tags are not allowed on %s:", keyword)
+        p.tags = nil
+    }
+    bg := &Background{Location: loc, Keyword: keyword, Name: name}
+    if p.rule != nil {
+        p.rule.Background = bg
+    } else {
+        p.feature.Background = bg
+    }
+    p.steps = &bg.Steps
+    p.openDescription(&bg.Description)
+}
+
+func (p *parser) startRule(loc Location, keyword, name string) {
+    p.startHeader(loc, keyword)
+    p.rule = &Rule{Location: loc, Tags: p.takeTags(), Keyword: keyword, Name: name}
+    p.feature.Rules = append(p.feature.Rules, p.rule)
+    p.openDescription(&p.rule.Description)
+}
+
+func (p *parser) addScenarioDefinition(sd ScenarioDefinition) {
+    if p.rule != nil {
+        p.rule.Scenarios = append(p.rule.Scenarios, sd)
+    } else {
+        p.feature.Scenarios = append(p.feature.Scenarios, sd)
+    }
+}
+
+func (p *parser) startScenario(loc Location, keyword, name string) {
+    p.startHeader(loc, keyword)
+    s := &Scenario{Location: loc, Tags: p.takeTags(), Keyword: keyword, Name: name}
+    p.addScenarioDefinition(s)
+    p.steps = &s.Steps
+    p.openDescription(&s.Description)
+}
+
+func (p *parser) startScenarioOutline(loc Location, keyword, name string) {
+    p.startHeader(loc, keyword)
+    so := &ScenarioOutline{Location: loc, Tags: p.takeTags(), Keyword: keyword, Name: name}
+    p.addScenarioDefinition(so)
+    p.outline = so
+    p.steps = &so.Steps
+    p.openDescription(&so.Description)
+}
+
+func (p *parser) startExamples(loc Location, keyword, name string) {
+    p.closeDescription()
+    p.steps = nil
+    p.step = nil
+    p.examples = &Examples{Location: loc, Tags: p.takeTags(), Keyword: keyword, Name: name}
+    if p.outline != nil {
+        p.outline.Examples = append(p.outline.Examples, p.examples)
+    } else {
+        p.errorf(loc, "%s: must follow a scenario outline", keyword)
+    }
+    p.openDescription(&p.examples.Description)
+}
+
+func (p *parser) addStep(loc Location, keyword, text string) {
+    p.closeDescription()
+    p.step = nil
+    if p.steps == nil {
+        if p.examples != nil {
+            p.errorf(loc, "step %q after Examples:, steps must precede the examples", text)
+        } else {
+            p.errorf(loc, "step %q is outside of a scenario or background", text)
+        }
+        return
+    }
+    p.step = &Step{Location: loc, Keyword: keyword, Text: text}
+    *p.steps = append(*p.steps, p.step)
+}
+
+func (p *parser) addTableRow(line string, loc Location) {
+    p.closeDescription()
+    row, ok := parseTableRow(line, loc.Line)
+    if !ok {
+        p.errorf(loc, "malformed table row, expected a closing |")
+        return
+    }
+    if p.step != nil {
+        if p.step.DocString != nil {
+            p.errorf(loc, "step %q already has a doc string", p.step.Text)
+        } else if p.step.DataTable == nil {
+            p.step.DataTable = &DataTable{Location: loc, Rows: []*TableRow{row}}
+        } else if expected := len(p.step.DataTable.Rows[0].Cells); len(row.Cells) != expected {
+            p.errorf(loc, "inconsistent cell count, expected %d cells but found %d", expected, len(row.Cells))
+        } else {
+            p.step.DataTable.Rows = append(p.step.DataTable.Rows, row)
+        }
+    } else if p.examples != nil {
+        if p.examples.TableHeader == nil {
+            p.examples.TableHeader = row
+        } else if expected := len(p.examples.TableHeader.Cells); len(row.Cells) != expected {
+            p.errorf(loc, "inconsistent cell count, expected %d cells but found %d", expected, len(row.Cells))
+        } else {
+            p.examples.TableBody = append(p.examples.TableBody, row)
+        }
+    } else {
+        p.errorf(loc, "table row must follow a step or Examples:")
+    }
+}
+
+// Reads the doc string opened by delimiter on the current line. Content
+// lines are dedented by the indentation of the opening delimiter, and an
+// escaped delimiter within the content is unescaped.
+func (p *parser) readDocString(loc Location, delimiter, mediaType string) {
+    p.closeDescription()
+    escaped := strings.Repeat(`\` + delimiter[:1], 3)
+    content := []string{}
+    for p.lineNo++; p.lineNo < len(p.lines); p.lineNo++ {
+        line := strings.TrimRight(p.lines[p.lineNo], "\r")
+        if strings.TrimSpace(line) == delimiter {
+            p.setDocString(&DocString{
+                Location: loc,
+                Delimiter: delimiter,
+                MediaType: mediaType,
+                Content: strings.Join(content, "\n")})
+            return
+        }
+        line = dedent(line, loc.Column - 1)
+        content = append(content, strings.Replace(line, escaped, delimiter, -1))
+    }
+    p.errorf(loc, "unterminated doc string")
+}
+
+func (p *parser) setDocString(ds *DocString) {
+    if p.step == nil {
+        p.errorf(ds.Location, "doc string must follow a step")
+    } else if p.step.DataTable != nil {
+        p.errorf(ds.Location, "step %q already has a data table", p.step.Text)
+    } else if p.step.DocString != nil {
+        p.errorf(ds.Location, "step %q already has a doc string", p.step.Text)
+    } else {
+        p.step.DocString = ds
+    }
+}
+
+// Number of leading whitespace characters of line.
+func indentOf(line string) int {
+    n := 0
+    for _, c := range line {
+        if !unicode.IsSpace(c) {
+            break
+        }
+        n++
+    }
+    return n
+}
+
+// Removes up to n leading whitespace characters from line.
+func dedent(line string, n int) string {
+    runes := []rune(line)
+    i := 0
+    for i < n && i < len(runes) && unicode.IsSpace(runes[i]) {
+        i++
+    }
+    return string(runes[i:])
+}
+
+func parseTags(line string, lineNo int) []*Tag {
+    if i := strings.Index(line, " #"); i >= 0 {
+        line = line[:i]
+    }
+    tags := []*Tag{}
+    for _, idx := range tagToken.FindAllStringIndex(line, -1) {
+        column := len([]rune(line[:idx[0]])) + 1
+        tags = append(tags, &Tag{Location{lineNo, column}, line[idx[0]:idx[1]]})
+    }
+    return tags
+}
+
+// Splits a '|' delimited line into cells, honouring the \|, \\ and \n
+// escapes. Returns false if the line is not a complete table row.
+func parseTableRow(line string, lineNo int) (*TableRow, bool) {
+    runes := []rune(line)
+    start := indentOf(line)
+    row := &TableRow{Location: Location{lineNo, start + 1}}
+    var cell []rune
+    cellColumn := 0
+    for i := start + 1; i < len(runes); i++ {
+        c := runes[i]
+        if cellColumn == 0 && c != '|' && !unicode.IsSpace(c) {
+            cellColumn = i + 1
+        }
+        switch {
+        case c == '\\' && i + 1 < len(runes):
+            i++
+            switch runes[i] {
+            case '|':
+                cell = append(cell, '|')
+            case '\\':
+                cell = append(cell, '\\')
+            case 'n':
+                cell = append(cell, '\n')
+            default:
+                cell = append(cell, '\\', runes[i])
+            }
+        case c == '|':
+            if cellColumn == 0 {
+                cellColumn = i + 1
+            }
+            row.Cells = append(row.Cells, &TableCell{
+                Location{lineNo, cellColumn},
+                strings.TrimSpace(string(cell))})
+            cell = nil
+            cellColumn = 0
+        default:
+            cell = append(cell, c)
+        }
+    }
+    if strings.TrimSpace(string(cell)) != "" || len(row.Cells) == 0 {
+        return nil, false
+    }
+    return row, true
+}
@@ -0,0 +1,169 @@
+package gherkin
+
+import (
+    "strings"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func parse(t *testing.T, text string) *Feature {
+    f, err := ParseFeature(strings.NewReader(text))
+    AssertThat(t, err, Equals(nil))
+    return f
+}
+
+func TestParsesFeatureNameAndDescription(t *testing.T) {
+    f := parse(t, `Feature: My Feature
+    In order to test
+    the parser
+
+    Scenario: Scenario 1`)
+
+    AssertThat(t, f.Name, Equals("My Feature"))
+    AssertThat(t, f.Description, Equals("In order to test\nthe parser"))
+    AssertThat(t, f.Location, Equals(Location{1, 1}))
+}
+
+func TestParsesScenariosWithSteps(t *testing.T) {
+    f := parse(t, featureText)
+
+    AssertThat(t, len(f.Scenarios), Equals(3))
+    s := f.Scenarios[0].(*Scenario)
+    AssertThat(t, s.Name, Equals("Scenario 1"))
+    AssertThat(t, s.Location, Equals(Location{2, 5}))
+    AssertThat(t, len(s.Steps), Equals(4))
+    AssertThat(t, s.Steps[3].Keyword, Equals("But"))
+    AssertThat(t, s.Steps[3].Text, Equals("not the other first result"))
+    AssertThat(t, s.Steps[3].Location, Equals(Location{6, 9}))
+}
+
+func TestParsesBackground(t *testing.T) {
+    f := parse(t, `Feature:
+        Background:
+            Given background
+        Scenario:
+            Then this`)
+
+    AssertThat(t, f.Background.Steps[0].Text, Equals("background"))
+    AssertThat(t, len(f.Scenarios), Equals(1))
+}
+
+func TestParsesRulesWithTheirOwnBackground(t *testing.T) {
+    f := parse(t, `Feature:
+        Scenario: outside
+            Given a
+        Rule: a rule
+            Background:
+                Given b
+            Example: inside
+                Given c`)
+
+    AssertThat(t, len(f.Scenarios), Equals(1))
+    AssertThat(t, len(f.Rules), Equals(1))
+    AssertThat(t, f.Rules[0].Name, Equals("a rule"))
+    AssertThat(t, f.Rules[0].Background.Steps[0].Text, Equals("b"))
+    AssertThat(t, f.Rules[0].Scenarios[0].(*Scenario).Name, Equals("inside"))
+}
+
+func TestParsesDataTables(t *testing.T) {
+    f := parse(t, `Feature:
+        Scenario:
+            Given these people
+                |name|email|
+                | Bob | bob\|bob@bob.com |`)
+
+    table := f.Scenarios[0].(*Scenario).Steps[0].DataTable
+    AssertThat(t, len(table.Rows), Equals(2))
+    AssertThat(t, table.Rows[0].Values(), Equals([]string{"name", "email"}))
+    AssertThat(t, table.Rows[1].Values(), Equals([]string{"Bob", "bob|bob@bob.com"}))
+    AssertThat(t, table.Rows[1].Cells[1].Location, Equals(Location{5, 25}))
+}
+
+func TestParsesScenarioOutlineExamples(t *testing.T) {
+    f := parse(t, `Feature:
+        Scenario Outline: eating
+            Given there are <start> cucumbers
+        Examples: some
+            |start|
+            |12|
+            |20|`)
+
+    so := f.Scenarios[0].(*ScenarioOutline)
+    AssertThat(t, len(so.Examples), Equals(1))
+    AssertThat(t, so.Examples[0].Name, Equals("some"))
+    AssertThat(t, so.Examples[0].TableHeader.Values(), Equals([]string{"start"}))
+    AssertThat(t, len(so.Examples[0].TableBody), Equals(2))
+}
+
+func TestParsesDocStrings(t *testing.T) {
+    f := parse(t, `Feature:
+        Scenario:
+            Given a blog post
+              """
+              Some Title
+                indented
+              """`)
+
+    ds := f.Scenarios[0].(*Scenario).Steps[0].DocString
+    AssertThat(t, ds.Content, Equals("Some Title\n  indented"))
+    AssertThat(t, ds.Location, Equals(Location{4, 15}))
+}
+
+func TestParsesBacktickDocStringsWithMediaType(t *testing.T) {
+    f := parse(t, "Feature:\n  Scenario:\n    Given .\n      ```xml\n      <a>\\`\\`\\`</a>\n      ```")
+
+    ds := f.Scenarios[0].(*Scenario).Steps[0].DocString
+    AssertThat(t, ds.Delimiter, Equals("```"))
+    AssertThat(t, ds.MediaType, Equals("xml"))
+    AssertThat(t, ds.Content, Equals("<a>```</a>"))
+}
+
+func TestParsesTagsAndComments(t *testing.T) {
+    f := parse(t, `# a comment
+    @billing @slow
+    Feature:
+        @wip
+        Scenario:
+            Given .`)
+
+    AssertThat(t, len(f.Tags), Equals(2))
+    AssertThat(t, f.Tags[1].Name, Equals("@slow"))
+    AssertThat(t, f.Tags[1].Location, Equals(Location{2, 14}))
+    AssertThat(t, f.Scenarios[0].(*Scenario).Tags[0].Name, Equals("@wip"))
+    AssertThat(t, f.Comments[0].Text, Equals("# a comment"))
+}
+
+func TestReportsInconsistentCellCount(t *testing.T) {
+    _, err := ParseFeature(strings.NewReader(`Feature:
+        Scenario:
+            Given given
+                |name|addr|
+                |bob|`))
+
+    AssertThat(t, err.Error(), Equals("5:17: inconsistent cell count, expected 2 cells but found 1"))
+}
+
+func TestCollectsAllParseErrors(t *testing.T) {
+    _, err := ParseFeature(strings.NewReader(`Feature:
+    Given an orphan step
+    Scenario:
+        Given a step
+    Examples:
+        |a|
+    Scenario Outline:
+        Given <a>
+        """
+        unterminated`))
+
+    errs := err.(ParseErrors)
+    AssertThat(t, len(errs), Equals(3))
+    AssertThat(t, errs[0].Line, Equals(2))
+    AssertThat(t, errs[1].Line, Equals(5))
+    AssertThat(t, errs[2].Line, Equals(9))
+}
+
+func TestParseErrorsNameTheFile(t *testing.T) {
+    err := &ParseError{"features/login.feature", Location{3, 5}, "oops"}
+
+    AssertThat(t, err.Error(), Equals("features/login.feature:3:5: oops"))
+}
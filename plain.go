@@ -0,0 +1,117 @@
+package gherkin
+
+import (
+    "fmt"
+    "io"
+    "strings"
+)
+
+// Writes the feature as it executes, each step prefixed by what happened
+// to it, followed by the summary printed by PrintReport().
+type plainFormatter struct {
+    output io.Writer
+    rule *Rule
+    definition ScenarioDefinition
+    examples *Examples
+    background *Background
+    // The scenario header, written before its first step.
+    header string
+    // Whether to end the run with PrintReport().
+    summary bool
+}
+
+// The formatter a Runner uses for the output set with SetOutput().
+func NewPlainFormatter(w io.Writer) Formatter {
+    return &plainFormatter{output: w, summary: true}
+}
+
+func (p *plainFormatter) RunStarted(glue *Glue) {}
+
+func (p *plainFormatter) FeatureStarted(feature *Feature, uri string) {
+    p.rule, p.definition, p.examples, p.background, p.header = nil, nil, nil, nil, ""
+    if feature.Keyword != "" {
+        fmt.Fprintf(p.output, "%s\n", renderHeader(feature.Location, feature.Keyword, feature.Name))
+    }
+}
+
+func (p *plainFormatter) ScenarioStarted(scenario *ScenarioInfo) {
+    if scenario.Rule != nil && scenario.Rule != p.rule {
+        rule := scenario.Rule
+        fmt.Fprintf(p.output, "%s\n", renderHeader(rule.Location, rule.Keyword, rule.Name))
+    }
+    p.rule = scenario.Rule
+    header := ""
+    switch def := scenario.Definition.(type) {
+    case *Scenario:
+        header = renderHeader(def.Location, def.Keyword, def.Name)
+    case *ScenarioOutline:
+        if def != p.definition {
+            fmt.Fprintf(p.output, "%s\n", renderHeader(def.Location, def.Keyword, def.Name))
+        }
+        if examples := scenario.Examples; examples != p.examples {
+            fmt.Fprintf(p.output, "%s\n", renderHeader(examples.Location, examples.Keyword, examples.Name))
+            fmt.Fprintf(p.output, "%s\n", renderTableRow(examples.TableHeader))
+        }
+        header = renderTableRow(scenario.Row)
+    }
+    p.definition, p.examples = scenario.Definition, scenario.Examples
+    if len(scenario.Tags) > 0 {
+        header = strings.Repeat(" ", indentOf(header)) + strings.Join(scenario.Tags, " ") + "\n" + header
+    }
+    p.header = header
+    p.background = nil
+}
+
+// Writes the scenario header, unless already written.
+func (p *plainFormatter) flushHeader() {
+    if p.header != "" {
+        fmt.Fprintf(p.output, "%s\n", p.header)
+        p.header = ""
+    }
+}
+
+func (p *plainFormatter) StepFinished(step *StepInfo, result *StepResult) {
+    if bg := step.Background; bg != nil {
+        if bg != p.background {
+            fmt.Fprintf(p.output, "%s\n", renderHeader(bg.Location, bg.Keyword, bg.Name))
+        }
+        p.background = bg
+    } else {
+        p.flushHeader()
+    }
+    line := step.Keyword + " " + step.Text
+    if step.Step != nil {
+        line = indent(step.Step.Location) + line
+    }
+    switch result.Status {
+    case StatusSkipped:
+        fmt.Fprintf(p.output, "Skipped - %s\n", line)
+    case StatusPending:
+        fmt.Fprintf(p.output, "PENDING - %s\n", line)
+    case StatusAmbiguous:
+        fmt.Fprintf(p.output, "AMBIGUOUS - %s\n", line)
+    case StatusUndefined:
+        fmt.Fprintf(p.output, "UNDEFINED - %s\n", line)
+    default:
+        fmt.Fprintf(p.output, "%s", line)
+    }
+    fmt.Fprintf(p.output, "\n\t%s\n", result.Output)
+}
+
+func (p *plainFormatter) HookFinished(scenario *ScenarioInfo, hook *HookInfo, result *StepResult) {
+    if result.Status == StatusFailed {
+        fmt.Fprintf(p.output, "HOOK FAILED - %s (%s): %s\n", hook.Kind, hook.Location, result.Err)
+    }
+}
+
+func (p *plainFormatter) ScenarioFinished(scenario *ScenarioInfo) {
+    p.flushHeader()
+}
+
+func (p *plainFormatter) FeatureFinished(feature *Feature) {}
+
+func (p *plainFormatter) RunFinished(rpt Report) {
+    if p.summary {
+        PrintReport(rpt, p.output)
+    }
+}
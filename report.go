@@ -7,4 +7,70 @@ type Report struct {
     passedSteps int
     failedSteps int
     undefinedSteps int
+    ambiguousSteps int
+    failedHooks int
+    // Suggested step definitions for the undefined steps, without
+    // duplicates.
+    snippets []string
+    // The pending, undefined and ambiguous steps, for strict mode.
+    incompleteSteps []string
+    err error
+}
+
+// Adds the counts and snippets of a scenario's or feature's report.
+func (rpt *Report) add(scenarioRpt Report) {
+    rpt.scenarioCount += scenarioRpt.scenarioCount
+    rpt.skippedSteps += scenarioRpt.skippedSteps
+    rpt.pendingSteps += scenarioRpt.pendingSteps
+    rpt.passedSteps += scenarioRpt.passedSteps
+    rpt.failedSteps += scenarioRpt.failedSteps
+    rpt.undefinedSteps += scenarioRpt.undefinedSteps
+    rpt.ambiguousSteps += scenarioRpt.ambiguousSteps
+    rpt.failedHooks += scenarioRpt.failedHooks
+    rpt.snippets = appendSnippets(rpt.snippets, scenarioRpt.snippets...)
+    rpt.incompleteSteps = append(rpt.incompleteSteps, scenarioRpt.incompleteSteps...)
+}
+
+// The outcome of a scenario.
+type Status int
+
+const (
+    StatusPassed Status = iota
+    StatusFailed
+    StatusPending
+    StatusUndefined
+    StatusAmbiguous
+    StatusSkipped
+    StatusHookFailed
+)
+
+var statusNames = []string{"passed", "failed", "pending", "undefined", "ambiguous", "skipped", "hook failed"}
+
+func (s Status) String() string {
+    return statusNames[s]
+}
+
+// The status of the scenario the report is for.
+func (rpt Report) status() Status {
+    switch {
+    case rpt.failedHooks > 0:
+        return StatusHookFailed
+    case rpt.failedSteps > 0:
+        return StatusFailed
+    case rpt.ambiguousSteps > 0:
+        return StatusAmbiguous
+    case rpt.undefinedSteps > 0:
+        return StatusUndefined
+    case rpt.pendingSteps > 0:
+        return StatusPending
+    case rpt.passedSteps > 0:
+        return StatusPassed
+    }
+    return StatusSkipped
+}
+
+// The error that prevented the feature from running, such as
+// ParseErrors, or nil.
+func (rpt Report) Err() error {
+    return rpt.err
 }
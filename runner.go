@@ -5,35 +5,40 @@ import (
     "strings"
     "fmt"
     "io"
-    "io/ioutil"
     "path/filepath"
     "os"
     "reflect"
+    "testing"
+    "time"
     matchers "github.com/tychofreeman/go-matchers"
 )
 
 type Runner struct {
     steps []stepdef
-    background Scenario
-    isExample bool
+    stepDefErrors []error
+    parameterTypes *parameterTypes
     setUp interface{}
     tearDown interface{}
-    currScenario Scenario
-    scenarios []Scenario
     output io.Writer
     ctx interface{}
-}
-
-func (r *Runner) addStepLine(line, orig string) {
-    r.currScenario.AddStep(StepFromStringAndOrig(line, orig))
-}
-
-func (r *Runner) currStepLine() step {
-    l := r.currStep()
-    if l == nil {
-        return StepFromString("")
-    }
-    return *l
+    contextFactory func() interface{}
+    concurrency int
+    // Whether the Runner said that SetConcurrency() has no effect.
+    warnedConcurrency bool
+    beforeHooks []hook
+    afterHooks []hook
+    beforeStepHooks []hook
+    afterStepHooks []hook
+    beforeAll []func() error
+    afterAll []func() error
+    tagFilter TagExpression
+    cmdTagFilter TagExpression
+    strictMatching bool
+    strict bool
+    dryRun bool
+    continueOnFailure bool
+    snippetStyle SnippetStyle
+    formatters []Formatter
 }
 
 // Register a set-up function to be called at the beginning of each scenario
@@ -46,232 +51,594 @@ func (r *Runner) SetTearDownFn(tearDown interface{}) {
     r.tearDown = tearDown
 }
 
-// The recommended way to create a gherkin.Runner object.
-func CreateRunner() *Runner {
-    s := []Scenario{}
-    return &Runner{[]stepdef{}, nil, false, nil, nil, nil, s, os.Stdout, nil}
-}
-
-func createWriterlessRunner() *Runner {
-    r := CreateRunner()
-    r.output = nil
-    return r
+// Build a new context for each scenario, passed to the set-up function,
+// the background and scenario steps and the tear-down function instead
+// of the context given to Run() or Execute().
+func (r *Runner) SetContextFactory(factory func() interface{}) {
+    r.contextFactory = factory
 }
 
-// Register a step definition. This requires a regular expression
-// pattern and a function to execute.
-func (r *Runner) RegisterStepDef(pattern string, f interface{}) {
-    r.steps = append(r.steps, createstepdef(pattern, f))
+// Register a hook to run before each scenario matching the tag
+// expression, or every scenario if it is empty, after the set-up
+// function. The hook is a func(*gherkin.ScenarioInfo, context) and may
+// return an error; a hook that fails or panics marks the scenario as
+// hook failed and its steps are skipped.
+func (r *Runner) Before(tags string, hook interface{}) error {
+    return r.BeforeWithOrder(0, tags, hook)
 }
 
-func (r *Runner) callFunc(f interface{}) {
-    t := reflect.TypeOf(f)
-    in := make([]reflect.Value, t.NumIn())
-    if len(in) != 1 {
-        panic("Function type mismatch")
+// Like Before(), but Before hooks run in ascending order, and those of
+// equal order in registration order.
+func (r *Runner) BeforeWithOrder(order int, tags string, hook interface{}) error {
+    h, err := createHook(order, tags, hook, scenarioInfoType)
+    if err != nil {
+        r.stepDefErrors = append(r.stepDefErrors, err)
+        return err
     }
-    in[0] = reflect.ValueOf(r.ctx)
-    m := reflect.ValueOf(f)
-    m.Call(in)
+    r.beforeHooks = insertHook(r.beforeHooks, h)
+    return nil
 }
 
-func (r *Runner) callSetUp() {
-    if r.setUp != nil {
-        r.callFunc(r.setUp)
-    }
+// Register a hook to run after each scenario matching the tag
+// expression, before the tear-down function, even if a step panicked.
+// ScenarioInfo.Status holds the outcome of the scenario.
+func (r *Runner) After(tags string, hook interface{}) error {
+    return r.AfterWithOrder(0, tags, hook)
 }
 
-func (r *Runner) callTearDown() {
-    if r.tearDown != nil {
-        r.callFunc(r.tearDown)
+// Like After(), but After hooks run in descending order, and those of
+// equal order in reverse registration order.
+func (r *Runner) AfterWithOrder(order int, tags string, hook interface{}) error {
+    h, err := createHook(order, tags, hook, scenarioInfoType)
+    if err != nil {
+        r.stepDefErrors = append(r.stepDefErrors, err)
+        return err
     }
+    r.afterHooks = insertHook(r.afterHooks, h)
+    return nil
 }
 
-func (r *Runner) runBackground() {
-    if r.background != nil {
-        r.background.Execute(r.steps, r.output, r.ctx)
+// Register a hook to run before each executed step of the scenarios
+// matching the tag expression. The hook is a func(*gherkin.StepInfo,
+// context) and may return an error; if it fails or panics, the step and
+// the rest of the scenario are skipped.
+func (r *Runner) BeforeStep(tags string, hook interface{}) error {
+    h, err := createHook(0, tags, hook, stepInfoType)
+    if err != nil {
+        r.stepDefErrors = append(r.stepDefErrors, err)
+        return err
     }
+    r.beforeStepHooks = append(r.beforeStepHooks, h)
+    return nil
 }
 
-func parseAsStep(line string) (bool, string) {
-    givenMatch, _ := re.Compile(`^\s*(Given|When|Then|And|But|\*)\s+(.*?)\s*$`)
-    if s := givenMatch.FindStringSubmatch(line); s != nil && len(s) > 1 {
-        return true, s[2]
+// Register a hook to run after each executed step of the scenarios
+// matching the tag expression. StepInfo.Status holds the outcome of the
+// step.
+func (r *Runner) AfterStep(tags string, hook interface{}) error {
+    h, err := createHook(0, tags, hook, stepInfoType)
+    if err != nil {
+        r.stepDefErrors = append(r.stepDefErrors, err)
+        return err
     }
-    return false, ""
+    r.afterStepHooks = append(r.afterStepHooks, h)
+    return nil
 }
 
-func isScenarioOutline(line string) bool {
-    return lineMatches(`^\s*Scenario Outline:\s*(.*?)\s*$`, line)
+// Register a function to run once before the features of a Run(),
+// RunT(), RunFeature() or RunFeatureT() call, e.g. to start a database
+// shared by all scenarios. If it returns an error, no feature is run.
+func (r *Runner) BeforeAll(f func() error) {
+    r.beforeAll = append(r.beforeAll, f)
 }
 
-func isExampleLine(line string) bool {
-    return lineMatches(`^\s*Examples:\s*(.*?)\s*$`, line)
+// Register a function to run once after the features of a Run(), RunT(),
+// RunFeature() or RunFeatureT() call, even if a BeforeAll function
+// failed. AfterAll functions run in reverse registration order.
+func (r *Runner) AfterAll(f func() error) {
+    r.afterAll = append(r.afterAll, f)
 }
 
-func isScenarioLine(line string) (bool) {
-    return lineMatches(`^\s*Scenario:\s*(.*?)\s*$`, line)
+// Runs the BeforeAll functions, then run unless one of them failed, then
+// the AfterAll functions, reporting their errors to t.
+func (r *Runner) runSuite(t matchers.Errorable, run func()) {
+    if r.dryRun {
+        run()
+        return
+    }
+    defer func() {
+        for i := len(r.afterAll) - 1; i >= 0; i-- {
+            if err := r.afterAll[i](); err != nil {
+                t.Errorf("AfterAll: %s", err)
+            }
+        }
+    }()
+    for _, f := range r.beforeAll {
+        if err := f(); err != nil {
+            t.Errorf("BeforeAll: %s", err)
+            return
+        }
+    }
+    run()
 }
 
-func isFeatureLine(line string) bool {
-    return lineMatches(`Feature:\s*(.*?)\s*$`, line)
+// Execute up to n scenarios at the same time. Since scenarios must not
+// share a context, this only takes effect along with SetContextFactory();
+// without one, the Runner warns on its output and runs scenarios one at
+// a time. The events of each scenario are still sent to formatters in
+// order, and a panic in a scenario is raised again in the goroutine
+// running the features once the other scenarios are done. RunT() always
+// runs scenarios one at a time.
+func (r *Runner) SetConcurrency(n int) {
+    r.concurrency = n
 }
-func isBackgroundLine(line string) bool {
-    return lineMatches(`^\s*Background:`, line)
+
+// The recommended way to create a gherkin.Runner object.
+func CreateRunner() *Runner {
+    return &Runner{
+        steps: []stepdef{},
+        parameterTypes: defaultParameterTypes(),
+        output: os.Stdout,
+        tagFilter: tagTrue{},
+        cmdTagFilter: tagTrue{}}
+}
+
+// Only run scenarios whose tags match the given tag expression, such as
+// "@smoke and not @slow". An empty expression runs every scenario.
+// Run() and RunFeature() additionally apply the -gherkin.tags flag or
+// the GHERKIN_TAGS environment variable.
+func (r *Runner) SetTagFilter(expr string) error {
+    filter, err := ParseTagExpression(expr)
+    if err != nil {
+        return err
+    }
+    r.tagFilter = filter
+    return nil
 }
 
-func lineMatches(spec, line string) bool {
-    featureMatch, _ := re.Compile(spec)
-    if s := featureMatch.FindStringSubmatch(line); s != nil {
-        return true
+// Picks up the tag expression given on the command line, reporting
+// false if it is invalid.
+func (r *Runner) useCommandLineTagFilter(t matchers.Errorable) bool {
+    filter, err := commandLineTagFilter()
+    if err != nil {
+        t.Errorf("%s", err)
+        return false
     }
-    return false
+    r.cmdTagFilter = filter
+    return true
 }
 
-func parseTableLine(line string) (fields []string) {
-    mlMatch, _ := re.Compile(`^\s*\|.*\|\s*$`)
-    if mlMatch.MatchString(line) {
-        tmpFields := strings.Split(line, "|")
-        fields = tmpFields[1:len(tmpFields)-1]
-        for i, f := range fields {
-            fields[i] = strings.TrimSpace(f)
+// Drops the scenarios whose tags don't match the tag filters.
+func (r *Runner) selectScenarios(runnables []runnable) []runnable {
+    selected := []runnable{}
+    for _, rn := range runnables {
+        if s, ok := rn.(*scenario); ok {
+            if !r.tagFilter.Evaluate(s.tags) || !r.cmdTagFilter.Evaluate(s.tags) {
+                continue
+            }
         }
+        selected = append(selected, rn)
     }
-    return
+    return selected
+}
+
+func createWriterlessRunner() *Runner {
+    r := CreateRunner()
+    r.output = nil
+    return r
 }
 
-func createTableMap(keys []string, fields []string) (l map[string]string) {
-    l = map[string]string{}
-    for i, k := range keys {
-        l[k] = fields[i]
+// Register a step definition. This requires a pattern and a function
+// to execute. The pattern is a Cucumber Expression such as
+// "I have {int} cukes" if it is not anchored with ^ or $ and uses a
+// {parameter}; otherwise it is a regular expression. An invalid pattern
+// or a function that doesn't fit it is rejected with an error naming
+// both, which Run() and RunFeature() also report before running any
+// feature.
+func (r *Runner) RegisterStepDef(pattern string, f interface{}) error {
+    if isCucumberExpression(pattern, r.parameterTypes) {
+        return r.RegisterExpressionStepDef(pattern, f)
     }
-    return
+    return r.RegisterRegexStepDef(pattern, f)
 }
 
-func (r *Runner) resetWithScenario(s Scenario) {
-    r.isExample = false
-    r.scenarios = append(r.scenarios, s)
-    r.currScenario = r.scenarios[len(r.scenarios)-1]
+// Register a step definition whose pattern is always read as a regular
+// expression.
+func (r *Runner) RegisterRegexStepDef(pattern string, f interface{}) error {
+    sd, err := createstepdef(pattern, f, r.parameterTypes)
+    return r.addStepDef(sd, err)
 }
 
-func (r *Runner) startScenarioOutline() {
-    r.resetWithScenario(&scenario_outline{})
+// Register a step definition whose pattern is always read as a
+// Cucumber Expression, supporting {int}, {float}, {word}, {string} and
+// {} parameters, optional text such as cuke(s) and alternation such as
+// belly/stomach.
+func (r *Runner) RegisterExpressionStepDef(expr string, f interface{}) error {
+    sd, err := createExpressionStepdef(expr, f, r.parameterTypes)
+    return r.addStepDef(sd, err)
 }
 
-func (r *Runner) startBackground(orig string) {
-    r.resetWithScenario(&scenario{orig: orig, isBackground: true})
+// By default a step runs the first step definition matching it. With
+// strict matching, a step matching several step definitions is reported
+// as AMBIGUOUS, listing every matching pattern and where it is declared.
+func (r *Runner) SetStrictMatching(strict bool) {
+    r.strictMatching = strict
 }
 
-func (r *Runner) startScenario(orig string) {
-    r.resetWithScenario(&scenario{orig: orig})
+// In strict mode, Run(), RunFeature(), RunT() and RunFeatureT() fail
+// the test for each pending, undefined or ambiguous step, naming it.
+// Strict mode implies strict matching. The -gherkin.strict flag also
+// enables strict mode.
+func (r *Runner) SetStrict(strict bool) {
+    r.strict = strict
 }
 
-func (r *Runner) currStep() *step {
-    if r.currScenario != nil {
-        return r.currScenario.Last()
-    }
-    return nil
+func (r *Runner) isStrict() bool {
+    return r.strict || *strictFlag
+}
+
+// In a dry run, steps are matched against the step definitions and
+// their arguments converted, but no step function, hook, set-up or
+// tear-down function is called. Matched steps are reported as skipped,
+// so that only undefined, ambiguous and unconvertible steps remain. A
+// dry run implies strict matching.
+func (r *Runner) SetDryRun(dryRun bool) {
+    r.dryRun = dryRun
+}
+
+// By default, the steps after a failed or undefined step are skipped.
+// With continueOnFailure, they are executed anyway. Steps after a
+// pending or ambiguous step, or one that panicked, are always skipped.
+func (r *Runner) SetContinueOnFailure(continueOnFailure bool) {
+    r.continueOnFailure = continueOnFailure
+}
+
+// Choose whether the snippets suggested for undefined steps use regular
+// expressions, the default, or Cucumber Expressions.
+func (r *Runner) SetSnippetStyle(style SnippetStyle) {
+    r.snippetStyle = style
+}
+
+// A step that matches more than one step definition.
+type AmbiguousStep struct {
+    Step *Step
+    // The step text, with scenario outline placeholders filled in.
+    Text string
+    // Each matching pattern followed by where its function is declared.
+    StepDefs []string
 }
 
+func (a AmbiguousStep) String() string {
+    return fmt.Sprintf("%d:%d: %q matches %s", a.Step.Line, a.Step.Column, a.Text, strings.Join(a.StepDefs, ", "))
+}
 
-func (r *Runner) setMlKeys(data []string) {
-    r.currStep().setMlKeys(data)
-}
-
-func (r *Runner) addMlStep(data map[string]string) {
-    r.currStep().addMlData(data)
-}
-func (r *Runner) addPrintableLine(line string) {
-    r.scenarios = append(r.scenarios, &printable_line{line})
-}
-
-func (r *Runner) step(line string) {
-    fields := parseTableLine(line)
-    isStep, data := parseAsStep(line)
-    if r.currScenario != nil && isStep {
-        r.addStepLine(data, line)
-    } else if isScenarioOutline(line) {
-        r.startScenarioOutline()
-    } else if isScenarioLine(line) {
-        r.startScenario(line)
-    } else if isFeatureLine(line) {
-        r.addPrintableLine(line)
-    } else if isBackgroundLine(line) {
-        r.startBackground(line)
-        r.background = r.currScenario
-    } else if isExampleLine(line) {
-        r.addPrintableLine(line)
-        r.isExample = true
-    } else if r.isExample && len(fields) > 0 {
-        r.addPrintableLine(line)
-        switch scen := r.currScenario.(type) {
-            case *scenario_outline:
-                if scen.keys == nil {
-                    scen.keys = fields
-                } else {
-                    newScenario := scen.CreateForExample(createTableMap(scen.keys, fields))
-                    r.scenarios = append(r.scenarios, &newScenario)
+// Checks every step of the features, including backgrounds and expanded
+// scenario outlines, against the registered step definitions. Returns
+// the steps matching more than one in document order, whether or not
+// strict matching is enabled.
+func (r *Runner) CheckAmbiguities(features ...*Feature) []AmbiguousStep {
+    ambiguous := []AmbiguousStep{}
+    seen := map[*Step]map[string]bool{}
+    for _, feature := range features {
+        for _, rn := range compileFeature(feature) {
+            scen, ok := rn.(*scenario)
+            if !ok {
+                continue
+            }
+            steps := []step{}
+            for _, bg := range scen.backgrounds {
+                steps = append(steps, bg.steps...)
+            }
+            steps = append(steps, scen.steps...)
+            for _, st := range steps {
+                if seen[st.source] == nil {
+                    seen[st.source] = map[string]bool{}
                 }
-            default:
-        }
-    } else if r.currStep() != nil && len(fields) > 0 {
-        r.addPrintableLine(line)
-        s := *r.currStep()
-        if len(s.keys) == 0 {
-            r.setMlKeys(fields)
-        } else if len(fields) != len(s.keys) {
-            panic(fmt.Sprintf("Wrong number of fields in multi-line step [%v] - expected %d fields but found %d", line, len(s.keys), len(fields)))
-        } else if len(fields) > 0 {
-            l := createTableMap(s.keys, fields)
-            r.addMlStep(l)
+                if seen[st.source][st.line] {
+                    continue
+                }
+                seen[st.source][st.line] = true
+                matches := matchingStepDefs(r.steps, &st)
+                if len(matches) < 2 {
+                    continue
+                }
+                a := AmbiguousStep{Step: st.source, Text: st.line}
+                for _, stepd := range matches {
+                    a.StepDefs = append(a.StepDefs, fmt.Sprintf("%s (%s)", stepd, stepd.location))
+                }
+                ambiguous = append(ambiguous, a)
+            }
         }
-    } else {
-        r.addPrintableLine(line)
+    }
+    return ambiguous
+}
+
+// Register a parameter type for use as {name} in Cucumber Expressions.
+// The transformer, a func(string) T or func(string) (T, error),
+// converts the matches of {name} into step arguments of type T. Unless T
+// is a type converted without one, such as string or int, it also
+// converts the captures of regular expressions for step arguments of
+// type T, and no other parameter type may transform into T. Register
+// parameter types before the step definitions using them.
+func (r *Runner) RegisterParameterType(name, regexp string, transformer interface{}) error {
+    return r.parameterTypes.register(name, regexp, transformer)
+}
+
+func (r *Runner) addStepDef(sd stepdef, err error) error {
+    if err != nil {
+        r.stepDefErrors = append(r.stepDefErrors, err)
+        return err
+    }
+    r.steps = append(r.steps, sd)
+    return nil
+}
+
+// Reports the errors of rejected step definitions and hooks, returning
+// false if there were any.
+func (r *Runner) checkStepDefs(t matchers.Errorable) bool {
+    for _, err := range r.stepDefErrors {
+        t.Errorf("%s", err)
+    }
+    return len(r.stepDefErrors) == 0
+}
+
+func (r *Runner) callFunc(f interface{}, ctx interface{}) {
+    t := reflect.TypeOf(f)
+    in := make([]reflect.Value, t.NumIn())
+    if len(in) != 1 {
+        panic("Function type mismatch")
+    }
+    in[0] = reflect.ValueOf(ctx)
+    m := reflect.ValueOf(f)
+    m.Call(in)
+}
+
+func (r *Runner) callSetUp(ctx interface{}) {
+    if r.setUp != nil {
+        r.callFunc(r.setUp, ctx)
+    }
+}
+
+func (r *Runner) callTearDown(ctx interface{}) {
+    if r.tearDown != nil {
+        r.callFunc(r.tearDown, ctx)
     }
 }
 
-func (r *Runner) executeScenario(scenario Scenario) Report{
+// Executes the backgrounds of the scenario, then its steps, which are
+// skipped if a background step fails or is pending, undefined or
+// ambiguous. If skipping is set, as after a failed Before hook, all of
+// them are reported as skipped. The report covers both.
+func (r *Runner) runWithBackground(scen *scenario, env *execution, skipping bool) Report {
     rpt := Report{}
-    if !scenario.IsBackground() {
-        if !scenario.IsJustPrintable() {
-            r.callSetUp()
-            r.runBackground()
+    for _, bg := range scen.backgrounds {
+        withTags := *bg
+        withTags.tags = scen.tags
+        var bgRpt Report
+        bgRpt, skipping = withTags.run(env, skipping)
+        rpt.add(bgRpt)
+    }
+    scenarioRpt, _ := scen.run(env, skipping)
+    rpt.add(scenarioRpt)
+    return rpt
+}
+
+// The context for the next scenario: a new one from the context
+// factory if there is one, otherwise the context given to Execute().
+// A dry run never calls the factory, as it calls no user code.
+func (r *Runner) scenarioContext() interface{} {
+    if r.contextFactory != nil && !r.dryRun {
+        return r.contextFactory()
+    }
+    return r.ctx
+}
+
+// Executes a scenario, sending its events to f, which may be nil. The
+// After hooks and tear-down function run even if a step panics. If a
+// Before hook fails, the steps are skipped.
+func (r *Runner) executeScenario(rn runnable, f Formatter) (rpt Report) {
+    if rn.IsBackground() {
+        return
+    }
+    env := r.execution(r.ctx, f)
+    scen, ok := rn.(*scenario)
+    if !ok {
+        return rn.Execute(env)
+    }
+    env.ctx = r.scenarioContext()
+    info := scen.info()
+    info.Started = time.Now()
+    env.scenario = info
+    if f != nil {
+        f.ScenarioStarted(info)
+    }
+    panicking := true
+    defer func() {
+        info.Status = rpt.status()
+        if panicking {
+            info.Status = StatusFailed
+        }
+        if !r.dryRun {
+            rpt.failedHooks += runHooks(r.afterHooks, true, "After", info, info.Tags, env)
+            r.callTearDown(env.ctx)
+            info.Status = rpt.status()
         }
-        rpt = scenario.Execute(r.steps, r.output, r.ctx)
-        if !scenario.IsJustPrintable() {
-            r.callTearDown()
+        info.Finished = time.Now()
+        if f != nil {
+            f.ScenarioFinished(info)
         }
+    }()
+    if r.dryRun {
+        rpt = r.runWithBackground(scen, env, false)
+        panicking = false
+        return
     }
-    return rpt
+    r.callSetUp(env.ctx)
+    if failed := runHooks(r.beforeHooks, false, "Before", info, info.Tags, env); failed > 0 {
+        rpt = r.runWithBackground(scen, env, true)
+        rpt.failedHooks = failed
+    } else {
+        rpt = r.runWithBackground(scen, env, false)
+    }
+    panicking = false
+    return
 }
 
-func (r *Runner) executeScenarios(scenarios []Scenario) Report {
+func (r *Runner) executeScenarios(scenarios []runnable, f Formatter) Report {
     rpt := Report{}
-    for _, scenario := range scenarios {
-        scenarioRpt := r.executeScenario(scenario)
+    for _, scenarioRpt := range r.executeAll(scenarios, f) {
         rpt.scenarioCount++
-        rpt.skippedSteps += scenarioRpt.skippedSteps
-        rpt.pendingSteps += scenarioRpt.pendingSteps
-        rpt.passedSteps += scenarioRpt.passedSteps
-        rpt.failedSteps += scenarioRpt.failedSteps
-        rpt.undefinedSteps += scenarioRpt.undefinedSteps
+        rpt.add(scenarioRpt)
     }
     return rpt
 }
 
+// Executes the scenarios, concurrently if the Runner allows it, and
+// returns their reports in order. The events of concurrent scenarios are
+// recorded and sent to f in the original order.
+func (r *Runner) executeAll(scenarios []runnable, f Formatter) []Report {
+    reports := make([]Report, len(scenarios))
+    if r.concurrency > 1 && r.contextFactory == nil && !r.warnedConcurrency {
+        r.warnedConcurrency = true
+        if r.output != nil {
+            fmt.Fprintf(r.output, "Warning: SetConcurrency(%d) has no effect without SetContextFactory()\n", r.concurrency)
+        }
+    }
+    if r.concurrency < 2 || r.contextFactory == nil {
+        for i, scenario := range scenarios {
+            reports[i] = r.executeScenario(scenario, f)
+        }
+        return reports
+    }
+    events := make([]*recordedEvents, len(scenarios))
+    panics := make([]interface{}, len(scenarios))
+    done := make([]chan bool, len(scenarios))
+    for i := range scenarios {
+        events[i] = &recordedEvents{}
+        done[i] = make(chan bool)
+    }
+    jobs := make(chan int)
+    for w := 0; w < r.concurrency; w++ {
+        go func() {
+            for i := range jobs {
+                func() {
+                    defer close(done[i])
+                    defer func() { panics[i] = recover() }()
+                    reports[i] = r.executeScenario(scenarios[i], events[i])
+                }()
+            }
+        }()
+    }
+    go func() {
+        for i := range scenarios {
+            jobs <- i
+        }
+        close(jobs)
+    }()
+    var panicked interface{}
+    for i := range scenarios {
+        <-done[i]
+        if panicked == nil && panics[i] != nil {
+            panicked = panics[i]
+        }
+        if f != nil && panicked == nil {
+            events[i].replay(f)
+        }
+    }
+    if panicked != nil {
+        panic(panicked)
+    }
+    return reports
+}
+
+func (r *Runner) execution(ctx interface{}, f Formatter) *execution {
+    env := &execution{
+        stepdefs: r.steps,
+        formatter: f,
+        ctx: ctx,
+        strictMatching: r.strictMatching || r.isStrict() || r.dryRun,
+        continueOnFailure: r.continueOnFailure || r.dryRun,
+        dryRun: r.dryRun,
+        snippetStyle: r.snippetStyle}
+    if !r.dryRun {
+        env.beforeStepHooks = r.beforeStepHooks
+        env.afterStepHooks = r.afterStepHooks
+    }
+    return env
+}
+
 func (r *Runner) resetWithContext(ctx interface{}) {
     r.ctx = ctx
 }
 
+// Attach a formatter receiving the events of every run, in addition to
+// the plain text written to the output set with SetOutput().
+func (r *Runner) AddFormatter(f Formatter) {
+    r.formatters = append(r.formatters, f)
+}
+
+// The step definitions and hooks, for formatters.
+func (r *Runner) glue() *Glue {
+    glue := &Glue{}
+    for _, s := range r.steps {
+        glue.StepDefs = append(glue.StepDefs, StepMatch{Pattern: s.String(), Expression: s.expr != "", Location: s.location})
+    }
+    kinds := []struct {
+        kind string
+        hooks []hook
+    }{
+        {"Before", r.beforeHooks},
+        {"After", r.afterHooks},
+        {"BeforeStep", r.beforeStepHooks},
+        {"AfterStep", r.afterStepHooks}}
+    for _, k := range kinds {
+        for _, h := range k.hooks {
+            glue.Hooks = append(glue.Hooks, &HookInfo{Kind: k.kind, Location: h.location})
+        }
+    }
+    return glue
+}
+
+// The formatters for a new run: plain text to the output, if any, and
+// those added with AddFormatter(). Only Run() and friends print the
+// summary.
+func (r *Runner) runFormatter(summary bool) Formatter {
+    fs := formatters{}
+    if r.output != nil {
+        fs = append(fs, &plainFormatter{output: r.output, summary: summary})
+    }
+    return append(fs, r.formatters...)
+}
+
 // Once the step definitions are Register()'d, use Execute() to
-// parse and execute Gherkin data.
+// parse and execute Gherkin data. If the data can't be parsed, nothing
+// is executed and Report.Err() holds the ParseErrors.
 func (r *Runner) Execute(file string, ctx interface{}) Report {
-    r.resetWithContext(ctx)
-    lines := strings.Split(file, "\n")
-    for _, line := range lines {
-        r.step(line)
+    feature, err := ParseFeature(strings.NewReader(file))
+    if err != nil {
+        if r.output != nil {
+            fmt.Fprintf(r.output, "%s\n", err)
+        }
+        return Report{err: err}
     }
-    return r.executeScenarios(r.scenarios)
+    return r.ExecuteFeature(feature, ctx)
+}
+
+// Executes a feature previously obtained from ParseFeature().
+func (r *Runner) ExecuteFeature(feature *Feature, ctx interface{}) Report {
+    f := r.runFormatter(false)
+    f.RunStarted(r.glue())
+    rpt := r.executeFeature(feature, "", ctx, f)
+    f.RunFinished(rpt)
+    return rpt
+}
+
+func (r *Runner) executeFeature(feature *Feature, uri string, ctx interface{}, f Formatter) Report {
+    r.resetWithContext(ctx)
+    f.FeatureStarted(feature, uri)
+    rpt := r.executeScenarios(r.selectScenarios(compileFeature(feature)), f)
+    f.FeatureFinished(feature)
+    return rpt
 }
 
 func generateStepReport(count int, name string) string {
@@ -296,32 +663,83 @@ func PrintReport(rpt Report, output io.Writer) {
     stepSpecifics = addCount(stepSpecifics, rpt.failedSteps, "failed")
     stepSpecifics = addCount(stepSpecifics, rpt.pendingSteps, "pending")
     stepSpecifics = addCount(stepSpecifics, rpt.undefinedSteps, "undefined")
+    stepSpecifics = addCount(stepSpecifics, rpt.ambiguousSteps, "ambiguous")
     subset := strings.Join(stepSpecifics, ", ")
     if len(subset) > 0 {
         subset = "(" + subset + ")"
     }
 
-    totalSteps := rpt.skippedSteps + rpt.passedSteps + rpt.failedSteps + rpt.pendingSteps + rpt.undefinedSteps
+    totalSteps := rpt.skippedSteps + rpt.passedSteps + rpt.failedSteps + rpt.pendingSteps + rpt.undefinedSteps + rpt.ambiguousSteps
     fmt.Fprintf(output, "%d scenarios\n%d steps%s\n", rpt.scenarioCount, totalSteps, subset)
+    if rpt.failedHooks > 0 {
+        fmt.Fprintf(output, "%d failed hooks\n", rpt.failedHooks)
+    }
+    if len(rpt.snippets) > 0 {
+        fmt.Fprintf(output, "\nYou can implement step definitions for undefined steps with these snippets:\n\n")
+        for _, snippet := range rpt.snippets {
+            fmt.Fprintf(output, "%s\n\n", snippet)
+        }
+    }
 }
 
+// Parses and executes a single feature file. Each parse error is
+// reported separately as file:line:column.
 func (r *Runner) RunFeature(t matchers.Errorable, ctx interface{}, filename string) {
-    file, err := os.Open(filename)
-    if err != nil {
-        t.Errorf(err.Error())
+    if !r.checkStepDefs(t) || !r.useCommandLineTagFilter(t) {
+        return
+    }
+    r.runSuite(t, func() { r.runFeatureFiles(t, ctx, []string{filename}) })
+}
+
+// Executes the feature files as a single run, reporting their results
+// once at the end.
+func (r *Runner) runFeatureFiles(t matchers.Errorable, ctx interface{}, filenames []string) {
+    f := r.runFormatter(true)
+    f.RunStarted(r.glue())
+    rpt := Report{}
+    for _, filename := range filenames {
+        rpt.add(r.runFeatureFile(t, ctx, filename, f))
+    }
+    f.RunFinished(rpt)
+}
+
+func (r *Runner) runFeatureFile(t matchers.Errorable, ctx interface{}, filename string, f Formatter) Report {
+    feature, err := ParseFeatureFile(filename)
+    if errs, ok := err.(ParseErrors); ok {
+        for _, e := range errs {
+            t.Errorf("%s", e)
+        }
+        return Report{err: err}
+    } else if err != nil {
+        t.Errorf("%s", err)
+        return Report{err: err}
     }
-    data, _ := ioutil.ReadAll(file)
-    rpt := r.Execute(string(data), ctx)
-    PrintReport(rpt, r.output)
-    if rpt.failedSteps > 0 {
-        t.Errorf("Failed %s", file)
+    rpt := r.executeFeature(feature, filename, ctx, f)
+    if rpt.failedSteps > 0 || rpt.failedHooks > 0 {
+        t.Errorf("Failed %s", filename)
     }
+    if r.isStrict() {
+        for _, s := range rpt.incompleteSteps {
+            t.Errorf("%s:%s", filename, s)
+        }
+    }
+    return rpt
 }
 
 // Once the step definitions are Register()'d, use Run() to
 // locate all *.feature files within the feature/ subdirectory
 // of the current directory.
 func (r *Runner) Run(t matchers.Errorable, ctx interface{}) {
+    if !r.checkStepDefs(t) || !r.useCommandLineTagFilter(t) {
+        return
+    }
+    r.runSuite(t, func() { r.runFeatureFiles(t, ctx, featureFiles()) })
+}
+
+// The *.feature files within the features/ subdirectory of the current
+// directory.
+func featureFiles() []string {
+    files := []string{}
     featureMatch, _ := re.Compile(`.*\.feature`)
     filepath.Walk("features", func(walkPath string, info os.FileInfo, err error) error {
         if err != nil {
@@ -330,22 +748,104 @@ func (r *Runner) Run(t matchers.Errorable, ctx interface{}) {
         if info.Name() != "features" && info.IsDir() {
             return filepath.SkipDir
         } else if !info.IsDir() && featureMatch.MatchString(info.Name()) {
-            file, _ := os.Open(walkPath)
-            data, _ := ioutil.ReadAll(file)
-            rpt := r.Execute(string(data), ctx)
-            PrintReport(rpt, r.output)
-            r.scenarios = []Scenario{}
-            r.background = nil
-            if rpt.failedSteps > 0 {
-                t.Errorf("Failed %s", file)
-            }
+            files = append(files, walkPath)
         }
         return nil
     })
+    return files
+}
+
+// Like Run(), but runs each feature as a subtest of t named after the
+// feature, and each scenario or example row as a nested subtest, so that
+// go test -run 'TestFeatures/Login/Valid_password' runs one scenario.
+// A failing scenario fails its own subtest; pending and undefined ones
+// are skipped.
+func (r *Runner) RunT(t *testing.T, ctx interface{}) {
+    if !r.checkStepDefs(t) || !r.useCommandLineTagFilter(t) {
+        return
+    }
+    r.runSuite(t, func() { r.runFeatureFilesT(t, ctx, featureFiles()) })
+}
+
+// Like RunFeature(), with the subtests of RunT().
+func (r *Runner) RunFeatureT(t *testing.T, ctx interface{}, filename string) {
+    if !r.checkStepDefs(t) || !r.useCommandLineTagFilter(t) {
+        return
+    }
+    r.runSuite(t, func() { r.runFeatureFilesT(t, ctx, []string{filename}) })
+}
+
+func (r *Runner) runFeatureFilesT(t *testing.T, ctx interface{}, filenames []string) {
+    f := r.runFormatter(true)
+    f.RunStarted(r.glue())
+    rpt := Report{}
+    for _, filename := range filenames {
+        rpt.add(r.runFeatureFileT(t, ctx, filename, f))
+    }
+    f.RunFinished(rpt)
+}
+
+func (r *Runner) runFeatureFileT(t *testing.T, ctx interface{}, filename string, f Formatter) Report {
+    feature, err := ParseFeatureFile(filename)
+    name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+    if feature != nil && feature.Name != "" {
+        name = feature.Name
+    }
+    rpt := Report{err: err}
+    t.Run(name, func(t *testing.T) {
+        if errs, ok := err.(ParseErrors); ok {
+            for _, e := range errs {
+                t.Errorf("%s", e)
+            }
+            return
+        } else if err != nil {
+            t.Errorf("%s", err)
+            return
+        }
+        rpt = r.executeFeatureT(t, feature, filename, ctx, f)
+    })
+    return rpt
+}
+
+// Executes each scenario of the feature in a subtest of t.
+func (r *Runner) executeFeatureT(t *testing.T, feature *Feature, uri string, ctx interface{}, f Formatter) Report {
+    r.resetWithContext(ctx)
+    f.FeatureStarted(feature, uri)
+    rpt := Report{}
+    for _, rn := range r.selectScenarios(compileFeature(feature)) {
+        scen := rn.(*scenario)
+        t.Run(scen.name, func(t *testing.T) {
+            // Counted here, so that scenarios go test -run filters out
+            // are not.
+            rpt.scenarioCount++
+            scenarioRpt := r.executeScenario(scen, f)
+            rpt.add(scenarioRpt)
+            failed := scenarioRpt.failedSteps > 0 || scenarioRpt.failedHooks > 0
+            if failed {
+                t.Errorf("Failed %s", strings.TrimSpace(scen.orig))
+            }
+            if r.isStrict() {
+                for _, s := range scenarioRpt.incompleteSteps {
+                    t.Errorf("%s", s)
+                }
+            } else if failed {
+                return
+            } else if scenarioRpt.pendingSteps > 0 {
+                t.Skip("pending")
+            } else if scenarioRpt.undefinedSteps > 0 {
+                t.Skip("undefined steps")
+            } else if scenarioRpt.ambiguousSteps > 0 {
+                t.Skip("ambiguous steps")
+            }
+        })
+    }
+    f.FeatureFinished(feature)
+    return rpt
 }
 
-// By default, Runner uses os.Stdout to write to. However, it may be useful
-// to redirect. To do so, provide an io.Writer here.
+// By default, Runner writes plain text to os.Stdout. However, it may be
+// useful to redirect. To do so, provide an io.Writer here, or nil to
+// only send events to the formatters added with AddFormatter().
 func (r *Runner) SetOutput(w io.Writer) {
     r.output = w
 }
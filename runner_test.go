@@ -3,7 +3,6 @@ package gherkin
 import (
     "testing"
     . "github.com/tychofreeman/go-matchers"
-    "io"
 )
 
 type MockScenario struct {
@@ -14,38 +13,75 @@ func (ms MockScenario) AddStep(s step) {
 func (ms MockScenario) Last() *step {
     return nil
 }
-func (ms MockScenario) Execute([]stepdef, io.Writer, interface{}) Report {
+func (ms MockScenario) Execute(*execution) Report {
     return ms.rpt
 }
 func (ms MockScenario) IsBackground() bool {
     return false
 }
 
-func (ms MockScenario) IsJustPrintable() bool {
-    return false
-}
-
 func TestReportsNumberOfScenarios(t *testing.T) {
-    scenarios := []Scenario{
-        MockScenario{rpt:Report{0,0,0,1,0,0}},
+    scenarios := []runnable{
+        MockScenario{rpt:Report{passedSteps:1}},
     }
 
     r := createWriterlessRunner()
-    rpt := r.executeScenarios(scenarios)
+    rpt := r.executeScenarios(scenarios, formatters{})
 
     AssertThat(t, rpt.scenarioCount, Equals(1))
 }
 
 func TestReportsNumberOfStepsInScenarios(t *testing.T) {
-    scenarios := []Scenario{
-        MockScenario{rpt:Report{0,2,2,2,2,2}},
+    scenarios := []runnable{
+        MockScenario{rpt:Report{pendingSteps:2, skippedSteps:2, passedSteps:2, failedSteps:2, undefinedSteps:2}},
     }
 
     r := createWriterlessRunner()
-    rpt := r.executeScenarios(scenarios)
+    rpt := r.executeScenarios(scenarios, formatters{})
 
     AssertThat(t, rpt.pendingSteps, Equals(2))
     AssertThat(t, rpt.skippedSteps, Equals(2))
     AssertThat(t, rpt.passedSteps, Equals(2))
     AssertThat(t, rpt.failedSteps, Equals(2))
 }
+
+func TestNamesScenariosAndExampleRows(t *testing.T) {
+    runnables := compileFeature(parse(t, `Feature:
+        Scenario: Valid password
+            Given .
+        Scenario Outline: Eating
+            Given <n>
+        Examples:
+            |n|
+            |1|
+            |2|
+    `))
+
+    names := []string{}
+    for _, rn := range runnables {
+        if s, ok := rn.(*scenario); ok {
+            names = append(names, s.name)
+        }
+    }
+    AssertThat(t, names, Equals([]string{"Valid password", "Eating #1", "Eating #2"}))
+}
+
+func TestRunsEachScenarioAsSubtest(t *testing.T) {
+    c := &Context{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("^pending$", func(w *World, ctx *Context) { Pending() })
+    g.RegisterStepDef("^count$", func(w *World, ctx *Context) { ctx.timesRun++ })
+
+    rpt := g.executeFeatureT(t, parse(t, `Feature:
+        Scenario: Pending
+            Given pending
+        Scenario: Counted
+            Given count
+            And count
+    `), "", c, formatters{})
+
+    AssertThat(t, c.timesRun, Equals(2))
+    AssertThat(t, rpt.scenarioCount, Equals(2))
+    AssertThat(t, rpt.pendingSteps, Equals(1))
+    AssertThat(t, rpt.passedSteps, Equals(2))
+}
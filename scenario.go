@@ -1,10 +1,36 @@
 package gherkin
 
-import (
-    "fmt"
-    "io"
-    re "regexp"
-)
+// Everything a scenario needs to run: the step definitions to match
+// steps against, where to send events, the context passed to each step
+// and the Runner's options.
+type execution struct {
+    stepdefs []stepdef
+    // Receives the events of the scenario; may be nil.
+    formatter Formatter
+    ctx interface{}
+    strictMatching bool
+    snippetStyle SnippetStyle
+    continueOnFailure bool
+    // Check steps without calling the step functions.
+    dryRun bool
+    beforeStepHooks []hook
+    afterStepHooks []hook
+    // The scenario being executed, passed to step hooks.
+    scenario *ScenarioInfo
+}
+
+func (env *execution) stepFinished(info *StepInfo, result *StepResult) {
+    info.Status = result.Status
+    if env.formatter != nil {
+        env.formatter.StepFinished(info, result)
+    }
+}
+
+func (env *execution) hookFinished(hook *HookInfo, result *StepResult) {
+    if env.formatter != nil {
+        env.formatter.HookFinished(env.scenario, hook, result)
+    }
+}
 
 type scenario_outline struct {
     steps []step
@@ -12,7 +38,7 @@ type scenario_outline struct {
     isPending bool
 }
 
-func ScenarioOutline() scenario_outline {
+func createScenarioOutline() scenario_outline {
     return scenario_outline{}
 }
 
@@ -23,13 +49,7 @@ func (so *scenario_outline) AddStep(s step) {
 func (so scenario_outline) CreateForExample(example map[string]string) scenario {
     s := scenario{}
     for _, currStep := range so.steps {
-        l := currStep.line
-
-        for k, v := range example {
-            r, _ := re.Compile("<" + k + ">")
-            l = r.ReplaceAllString(l, v)
-        }
-        s.steps = append(s.steps, StepFromString(l))
+        s.steps = append(s.steps, currStep.withExample(example))
     }
 
     return s
@@ -44,55 +64,36 @@ func (so *scenario_outline) Last() *step {
 
 func (so *scenario_outline) IsBackground() bool { return false }
 
-func (scen *scenario_outline) IsJustPrintable() bool { return false }
-
-func (so *scenario_outline) Execute(s []stepdef, output io.Writer,
-        ctx interface{}) Report {
-    return Report{}
-}
-
-type printable_line struct {
-    line string
-}
-
-func (uls *printable_line) AddStep(s step) {
-}
-
-func (uls *printable_line) Last() *step {
-    return nil
-}
-
-func (uls *printable_line)Execute(steps []stepdef, output io.Writer,
-        ctx interface{}) Report {
-    if output != nil {
-        fmt.Fprintf(output, "%s\n", uls.line)
-    }
+func (so *scenario_outline) Execute(env *execution) Report {
     return Report{}
 }
 
-func (uls *printable_line) IsBackground() bool {
-    return false
-}
-
-func (scen *printable_line) IsJustPrintable() bool { return true }
-
-type Scenario interface {
+type runnable interface {
     AddStep(step)
     Last() *step
-    Execute([]stepdef, io.Writer, interface{}) Report
+    Execute(*execution) Report
     IsBackground() bool
-    IsJustPrintable() bool
 }
 
 type scenario struct {
     steps []step
     isPending bool
     orig string
+    // The scenario name, numbered for example rows; used by RunT().
+    name string
+    feature string
+    location Location
+    // The AST nodes the scenario was compiled from.
+    definition ScenarioDefinition
+    rule *Rule
+    examples *Examples
+    row *TableRow
+    background *Background
     isBackground bool
+    backgrounds []*scenario
+    tags []string
 }
 
-func (scen *scenario) IsJustPrintable() bool { return false }
-
 func (scen *scenario) AddStep(stp step) {
     if scen.steps == nil {
         scen.steps = []step{stp}
@@ -108,49 +109,84 @@ func (s *scenario) Last() *step {
     return nil
 }
 
-func (s *scenario) Execute(stepdefs []stepdef, output io.Writer,
-        ctx interface{}) Report {
-    rpt := Report{}
-    if output != nil {
-        fmt.Fprintf(output, "%s\n", s.orig)
+func (s *scenario) info() *ScenarioInfo {
+    info := &ScenarioInfo{
+        Name: s.name,
+        Feature: s.feature,
+        Line: s.location.Line,
+        Tags: s.tags,
+        Definition: s.definition,
+        Rule: s.rule,
+        Examples: s.examples,
+        Row: s.row}
+    switch def := s.definition.(type) {
+    case *Scenario:
+        info.Keyword = def.Keyword
+    case *ScenarioOutline:
+        info.Keyword = def.Keyword
     }
-    isPending := false
+    return info
+}
+
+func (s *scenario) Execute(env *execution) Report {
+    rpt, _ := s.run(env, false)
+    return rpt
+}
+
+// Executes the steps, or reports them as skipped from the start if
+// skipping is set, as after a failed background step. Returns whether
+// the steps after these should be skipped.
+func (s *scenario) run(env *execution, skipping bool) (Report, bool) {
+    rpt := Report{}
+    isPending := skipping
     for _, line := range s.steps {
+        info := line.info(env, s.background)
         stepIsFound := true
+        hooksFailed := 0
         if !isPending {
-            stepIsFound = line.executeStepDef(stepdefs, ctx)
+            stepIsFound, hooksFailed = line.executeWithHooks(env, info, s.tags)
+            rpt.failedHooks += hooksFailed
         }
-        if !isPending && line.isPending {
+        status := StatusSkipped
+        if !isPending && hooksFailed > 0 && !line.executed {
+            rpt.skippedSteps++
+            isPending = true
+        } else if !isPending && line.isPending {
+            status = StatusPending
             rpt.pendingSteps++
-            if output != nil {
-                fmt.Fprintf(output, "PENDING - %s\n", line.orig)
-            }
+            rpt.incompleteSteps = append(rpt.incompleteSteps, line.describe("pending"))
+            isPending = true
+        } else if !isPending && line.isSkipped {
+            rpt.skippedSteps++
+            isPending = !env.dryRun
+        } else if !isPending && line.isAmbiguous {
+            status = StatusAmbiguous
+            rpt.ambiguousSteps++
+            rpt.incompleteSteps = append(rpt.incompleteSteps, line.describe("ambiguous"))
             isPending = true
         } else if isPending {
             rpt.skippedSteps++
-            if output != nil {
-                fmt.Fprintf(output, "Skipped - %s\n", line.orig)
-            }
         } else if !stepIsFound {
-                rpt.undefinedSteps++
-                if output != nil {
-                    fmt.Fprintf(output, "UNDEFINED - %s\n", line.orig)
-                }
+            status = StatusUndefined
+            rpt.undefinedSteps++
+            rpt.incompleteSteps = append(rpt.incompleteSteps, line.describe("undefined"))
+            rpt.snippets = appendSnippets(rpt.snippets, createSnippet(&line, env.ctx, env.snippetStyle))
+            isPending = !env.continueOnFailure
+        } else if line.hasErrors {
+            status = StatusFailed
+            rpt.failedSteps++
+            isPending = line.panicked || !env.continueOnFailure
         } else {
-            if line.hasErrors {
-                rpt.failedSteps++
-            } else {
-                rpt.passedSteps++
-            }
-            if output != nil {
-                fmt.Fprintf(output, "%s", line.orig)
-            }
+            status = StatusPassed
+            rpt.passedSteps++
         }
-        if output != nil {
-            fmt.Fprintf(output, "\n\t%v\n", &line.errors)
+        if hooksFailed > 0 {
+            // A failing AfterStep hook skips the rest, as a BeforeStep one does.
+            isPending = true
         }
+        env.stepFinished(info, line.result(status, line.duration))
     }
-    return rpt
+    return rpt, isPending
 }
 
 func (s *scenario) IsBackground() bool {
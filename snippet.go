@@ -0,0 +1,125 @@
+package gherkin
+
+import (
+    "fmt"
+    "reflect"
+    re "regexp"
+    "strconv"
+    "strings"
+)
+
+// How the snippets suggested for undefined steps express their pattern.
+type SnippetStyle int
+
+const (
+    // Anchored regular expressions, such as `^I have (\d+) apples$`.
+    RegexSnippets SnippetStyle = iota
+    // Cucumber Expressions, such as `I have {int} apples`, registered
+    // with RegisterExpressionStepDef() so that text without parameters
+    // isn't read as a regular expression.
+    ExpressionSnippets
+)
+
+// Numbers and double quoted strings in step text become parameters.
+var snippetParameter = re.MustCompile(`"[^"]*"|-?\b\d+(?:\.\d+)?\b`)
+
+// Escapes the characters with a special meaning in Cucumber Expressions.
+var expressionSpecial = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `{`, `\{`, `/`, `\/`)
+
+// A ready-to-paste step definition for an undefined step, e.g.
+//
+//     gherkin.Given(`^I have (\d+) apples$`, func(w *gherkin.World, ctx *MyCtx, arg1 int) {
+//         gherkin.Pending()
+//     })
+func createSnippet(s *step, ctx interface{}, style SnippetStyle) string {
+    pattern, args := "", []string{}
+    text := s.String()
+    last := 0
+    literal := func(in string) string {
+        if style == ExpressionSnippets {
+            return expressionSpecial.Replace(in)
+        }
+        return re.QuoteMeta(in)
+    }
+    for _, m := range snippetParameter.FindAllStringIndex(text, -1) {
+        param, goType := snippetArgument(text[m[0]:m[1]], style)
+        pattern += literal(text[last:m[0]]) + param
+        args = append(args, fmt.Sprintf("arg%d %s", len(args) + 1, goType))
+        last = m[1]
+    }
+    pattern += literal(text[last:])
+    if style == RegexSnippets {
+        pattern = "^" + pattern + "$"
+    }
+    if s.docString != nil {
+        args = append(args, "docString string")
+    }
+    params := append([]string{"w *gherkin.World", "ctx " + snippetContextType(ctx)}, args...)
+    register := s.keyword
+    if style == ExpressionSnippets {
+        register = "RegisterExpressionStepDef"
+    } else if register == "" {
+        register = "Given"
+    }
+    return fmt.Sprintf("gherkin.%s(%s, func(%s) {\n    gherkin.Pending()\n})",
+        register, snippetQuote(pattern), strings.Join(params, ", "))
+}
+
+// The pattern matching a number or quoted string, and the Go type it
+// converts to.
+func snippetArgument(match string, style SnippetStyle) (string, string) {
+    switch {
+    case strings.HasPrefix(match, `"`) && style == ExpressionSnippets:
+        return "{string}", "string"
+    case strings.HasPrefix(match, `"`):
+        return `"([^"]*)"`, "string"
+    case strings.Contains(match, ".") && style == ExpressionSnippets:
+        return "{float}", "float64"
+    case strings.Contains(match, "."):
+        return `(-?\d+\.\d+)`, "float64"
+    case style == ExpressionSnippets:
+        return "{int}", "int"
+    case strings.HasPrefix(match, "-"):
+        return `(-?\d+)`, "int"
+    }
+    return `(\d+)`, "int"
+}
+
+// The context type as written in the package declaring it.
+func snippetContextType(ctx interface{}) string {
+    t := reflect.TypeOf(ctx)
+    if t == nil {
+        return "interface{}"
+    }
+    prefix := ""
+    for t.Kind() == reflect.Ptr && t.Name() == "" {
+        prefix += "*"
+        t = t.Elem()
+    }
+    if t.Name() == "" {
+        return prefix + t.String()
+    }
+    return prefix + t.Name()
+}
+
+// Uses a raw string literal unless the pattern contains a backtick.
+func snippetQuote(pattern string) string {
+    if strings.Contains(pattern, "`") {
+        return strconv.Quote(pattern)
+    }
+    return "`" + pattern + "`"
+}
+
+// Appends the snippets not already in list.
+func appendSnippets(list []string, snippets ...string) []string {
+    for _, snippet := range snippets {
+        found := false
+        for _, s := range list {
+            found = found || s == snippet
+        }
+        if !found {
+            list = append(list, snippet)
+        }
+    }
+    return list
+}
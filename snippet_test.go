@@ -0,0 +1,75 @@
+package gherkin
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func snippetFor(t *testing.T, text string, style SnippetStyle) string {
+    steps := compileSteps(parse(t, "Feature:\n  Scenario:\n" + text).Scenarios[0].(*Scenario).Steps)
+    return createSnippet(&steps[len(steps) - 1], &Context{}, style)
+}
+
+func TestCreatesRegexSnippetWithInferredParameters(t *testing.T) {
+    snippet := snippetFor(t, `    Given I have 42 apples and "red" pears weighing 1.5 kg`, RegexSnippets)
+
+    AssertThat(t, snippet, Equals("gherkin.Given(`^I have (\\d+) apples and \"([^\"]*)\" pears weighing (-?\\d+\\.\\d+) kg$`, " +
+        "func(w *gherkin.World, ctx *Context, arg1 int, arg2 string, arg3 float64) {\n    gherkin.Pending()\n})"))
+}
+
+func TestCreatesExpressionSnippet(t *testing.T) {
+    snippet := snippetFor(t, `    Given I have 42 apple(s) and/or "red" pears`, ExpressionSnippets)
+
+    AssertThat(t, snippet, Equals("gherkin.RegisterExpressionStepDef(`I have {int} apple\\(s) and\\/or {string} pears`, " +
+        "func(w *gherkin.World, ctx *Context, arg1 int, arg2 string) {\n    gherkin.Pending()\n})"))
+}
+
+func TestExpressionSnippetWithoutParametersMatchesOnlyItsStep(t *testing.T) {
+    snippet := snippetFor(t, `    Given I am hungry.`, ExpressionSnippets)
+    AssertThat(t, strings.HasPrefix(snippet, "gherkin.RegisterExpressionStepDef(`I am hungry.`, "), IsTrue)
+
+    g := createWriterlessRunner()
+    g.RegisterExpressionStepDef("I am hungry.", func(w *World, ctx *Context) { })
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given I am hungry.
+        Scenario:
+            Given I am hungry!
+        Scenario:
+            Given so I am hungry now
+    `, &Context{})
+
+    AssertThat(t, rpt.passedSteps, Equals(1))
+    AssertThat(t, rpt.undefinedSteps, Equals(2))
+}
+
+func TestSnippetKeywordFollowsPrecedingStep(t *testing.T) {
+    snippet := snippetFor(t, "    When I eat\n    And I drink", RegexSnippets)
+
+    AssertThat(t, strings.HasPrefix(snippet, "gherkin.When(`^I drink$`"), IsTrue)
+}
+
+func TestSnippetTakesDocString(t *testing.T) {
+    snippet := snippetFor(t, "    Then I see\n      \"\"\"\n      text\n      \"\"\"", RegexSnippets)
+
+    AssertThat(t, strings.Contains(snippet, "ctx *Context, docString string)"), IsTrue)
+}
+
+func TestReportsEachSnippetOnce(t *testing.T) {
+    out := &bytes.Buffer{}
+    g := createWriterlessRunner()
+    rpt := g.Execute(`Feature:
+        Scenario:
+            Given I have 3 apples
+        Scenario:
+            Given I have 4 apples
+            And I have 3 apples
+    `, &Context{})
+    PrintReport(rpt, out)
+
+    AssertThat(t, rpt.undefinedSteps, Equals(2))
+    AssertThat(t, len(rpt.snippets), Equals(1))
+    AssertThat(t, strings.Count(out.String(), "gherkin.Pending()"), Equals(1))
+}
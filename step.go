@@ -2,7 +2,11 @@ package gherkin
 
 import (
     "bytes"
+    "errors"
     "fmt"
+    "runtime"
+    "strings"
+    "time"
 )
 
 type step struct {
@@ -10,7 +14,24 @@ type step struct {
     orig string
     keys []string
     mldata []map[string]string
+    // The data table, header included.
+    rows [][]string
+    docString *DocString
+    // The AST node the step was compiled from, if any.
+    source *Step
+    // Given, When or Then; And, But and * take the keyword of the step
+    // before them. Used for snippets of undefined steps.
+    keyword string
     isPending bool
+    isSkipped bool
+    // Whether the step failed by panicking.
+    panicked bool
+    isAmbiguous bool
+    // Whether the step was attempted, i.e. no BeforeStep hook failed.
+    executed bool
+    // When the step function was called and how long it took.
+    started time.Time
+    duration time.Duration
     errors bytes.Buffer
     hasErrors bool
 }
@@ -27,30 +48,200 @@ func StepFromStringAndOrig(in, orig string) step{
     return step{ line : in, orig: orig, keys: []string{}, mldata : []map[string]string{} }
 }
 
+// Returns a copy of the step with every <name> placeholder replaced
+// by the corresponding value of example.
+func (s step) withExample(example map[string]string) step {
+    replace := func(in string) string {
+        for k, v := range example {
+            in = strings.Replace(in, "<" + k + ">", v, -1)
+        }
+        return in
+    }
+    n := StepFromStringAndOrig(replace(s.line), replace(s.orig))
+    n.source = s.source
+    n.keyword = s.keyword
+    for _, k := range s.keys {
+        n.keys = append(n.keys, replace(k))
+    }
+    for _, row := range s.rows {
+        values := []string{}
+        for _, v := range row {
+            values = append(values, replace(v))
+        }
+        n.rows = append(n.rows, values)
+    }
+    for _, data := range s.mldata {
+        row := map[string]string{}
+        for k, v := range data {
+            row[replace(k)] = replace(v)
+        }
+        n.addMlData(row)
+    }
+    if s.docString != nil {
+        ds := *s.docString
+        ds.Content = replace(ds.Content)
+        n.docString = &ds
+    }
+    return n
+}
+
 func (s *step) addMlData(line map[string]string) {
     s.mldata = append(s.mldata, line)
 }
 
+// Turns a panic of the step function into a pending or skipped step for
+// ErrPending and ErrSkip, otherwise into a failed step reporting the
+// panic value and where it was raised.
 func (s *step) recoverPending() {
     if rec := recover(); rec != nil {
-        if rec == "Pending" {
-            s.isPending = true
+        if err, ok := rec.(error); ok && (errors.Is(err, ErrPending) || errors.Is(err, ErrSkip)) {
+            s.fail(err)
         } else {
-            panic(rec)
+            s.hasErrors = true
+            s.panicked = true
+            fmt.Fprintf(&s.errors, "panic: %v\n%s", rec, panicTrace())
         }
     }
 }
 
-func (currStep *step) executeStepDef(steps []stepdef, ctx interface{}) bool {
+// The stack of the panic being recovered, from where it was raised up
+// to the call of the step function, without runtime frames.
+func panicTrace() string {
+    pcs := make([]uintptr, 64)
+    frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+    trace := ""
+    for more := true; more; {
+        var frame runtime.Frame
+        frame, more = frames.Next()
+        if strings.HasPrefix(frame.Function, "reflect.") {
+            break
+        }
+        if !strings.HasPrefix(frame.Function, "runtime.") {
+            trace += fmt.Sprintf("\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+        }
+    }
+    return trace
+}
+
+// Marks the step as pending, skipped or failed with err.
+func (s *step) fail(err error) {
+    switch {
+    case errors.Is(err, ErrPending):
+        s.isPending = true
+    case errors.Is(err, ErrSkip):
+        s.isSkipped = true
+    default:
+        s.hasErrors = true
+        fmt.Fprintf(&s.errors, "%s\n", err)
+    }
+}
+
+// Executes the first step definition matching the step. With strict
+// matching, a step matching several step definitions is marked
+// ambiguous instead and none of them is executed.
+func (currStep *step) executeStepDef(env *execution, tags []string) (found bool) {
     defer currStep.recoverPending()
+    matches := matchingStepDefs(env.stepdefs, currStep)
+    if len(matches) == 0 {
+        fmt.Fprintf(&currStep.errors, `Could not find step definition for "%s"` + "\n", currStep.orig)
+        return false
+    }
+    if env.strictMatching && len(matches) > 1 {
+        currStep.isAmbiguous = true
+        fmt.Fprintf(&currStep.errors, `Ambiguous step definitions for "%s":` + "\n", currStep.orig)
+        for _, stepd := range matches {
+            fmt.Fprintf(&currStep.errors, "\t%s (%s)\n", stepd, stepd.location)
+        }
+        return true
+    }
+    found = true
+    matches[0].execute(currStep, env, tags)
+    return
+}
+
+// Executes the step between the BeforeStep and AfterStep hooks, unless
+// a BeforeStep hook fails. Returns whether a step definition was found
+// and how many hooks failed.
+func (currStep *step) executeWithHooks(env *execution, info *StepInfo, tags []string) (bool, int) {
+    failed := runHooks(env.beforeStepHooks, false, "BeforeStep", info, tags, env)
+    if failed > 0 {
+        return true, failed
+    }
+    currStep.executed = true
+    currStep.started = time.Now()
+    found := currStep.executeStepDef(env, tags)
+    currStep.duration = time.Since(currStep.started)
+    info.Status = currStep.status(found)
+    info.Result = currStep.result(info.Status, currStep.duration)
+    return found, failed + runHooks(env.afterStepHooks, true, "AfterStep", info, tags, env)
+}
+
+// Describes the step for hooks and formatters. The background is the
+// one the step belongs to, if any.
+func (s *step) info(env *execution, background *Background) *StepInfo {
+    info := &StepInfo{
+        Keyword: s.keyword,
+        Text: s.line,
+        Scenario: env.scenario,
+        Step: s.source,
+        DataTable: s.rows,
+        DocString: s.docString,
+        Background: background}
+    if s.source != nil {
+        info.Keyword = s.source.Keyword
+    }
+    matches := matchingStepDefs(env.stepdefs, s)
+    if len(matches) > 0 {
+        info.StepDef = matches[0].String()
+        info.StepDefLocation = matches[0].location
+    }
+    for _, m := range matches {
+        info.Matches = append(info.Matches, m.match(s.String()))
+    }
+    return info
+}
+
+func (s *step) result(status Status, duration time.Duration) *StepResult {
+    result := &StepResult{Status: status, Duration: duration, Started: s.started, Output: s.errors.String()}
+    switch status {
+    case StatusFailed, StatusUndefined, StatusAmbiguous:
+        result.Err = errors.New(strings.TrimSpace(result.Output))
+    }
+    return result
+}
+
+// The location and text of the step, prefixed by what happened to it.
+func (s *step) describe(what string) string {
+    if s.source == nil {
+        return fmt.Sprintf("%s step %q", what, strings.TrimSpace(s.orig))
+    }
+    return fmt.Sprintf("%d:%d: %s step %q", s.source.Line, s.source.Column, what, strings.TrimSpace(s.orig))
+}
+
+func (s *step) status(found bool) Status {
+    switch {
+    case s.isPending:
+        return StatusPending
+    case s.isSkipped:
+        return StatusSkipped
+    case s.isAmbiguous:
+        return StatusAmbiguous
+    case !found:
+        return StatusUndefined
+    case s.hasErrors:
+        return StatusFailed
+    }
+    return StatusPassed
+}
+
+func matchingStepDefs(steps []stepdef, currStep *step) []stepdef {
+    matches := []stepdef{}
     for _, stepd := range steps {
-            //fmt.Printf("Executing step %s with stepdef %d (%v)\n", currStep, i, stepd)
-        if stepd.execute(currStep, &currStep.errors, ctx) {
-            return true
+        if stepd.r.MatchString(currStep.String()) {
+            matches = append(matches, stepd)
         }
     }
-    fmt.Fprintf(&currStep.errors, `Could not find step definition for "%s"` + "\n", currStep.orig)
-    return false
+    return matches
 }
 
 func (s *step) setMlKeys(keys []string) {
@@ -1,89 +1,204 @@
 package gherkin
 
 import (
+    "fmt"
     re "regexp"
-    "io"
     "reflect"
-    "strconv"
+    "runtime"
 )
 
 type stepdef struct {
     r *re.Regexp
     f interface{}
+    // The Cucumber Expression r was compiled from, if any, and its
+    // parameter types in capture group order.
+    expr string
+    params []*parameterType
+    // Converts captures into arguments; may be nil.
+    types *parameterTypes
+    // Where the step function is declared.
+    location string
 }
 
-func (s stepdef) call(w *World) {
+// The arguments of the step function: the World, the context and one
+// argument per regex capture. When the step has a doc string, its
+// content may be passed as an additional trailing string argument.
+func (s stepdef) arguments(w *World) ([]reflect.Value, error) {
     t := reflect.TypeOf(s.f)
     in := make([]reflect.Value, t.NumIn())
     in[0] = reflect.ValueOf(w)
-    if len(in) != len(w.regexParams) + 1 {
-        panic("Function type mismatch")
+    params := len(w.regexParams) + 1
+    takesDocString := w.DocString != nil && len(in) == params + 1 &&
+        t.In(len(in) - 1).Kind() == reflect.String
+    if len(in) != params && !takesDocString {
+        return nil, stepdefError(s.String(), s.f, "function %s takes a doc string, but the step has none", t)
     }
     in[1] = reflect.ValueOf(w.ctx)
-    for i := 2; i < len(in); i++ {
-        var val interface{}
-        var err error
-        itp := w.regexParams[i - 1]
-        switch t.In(i).Kind() {
-        case reflect.Bool:
-            val, err = strconv.ParseBool(itp)
-        case reflect.Int8:
-            val, err = strconv.ParseInt(itp, 10, 8)
-            val = int8(val.(int64))
-        case reflect.Int16:
-            val, err = strconv.ParseInt(itp, 10, 16)
-            val = int16(val.(int64))
-        case reflect.Int32:
-            val, err = strconv.ParseInt(itp, 10, 32)
-            val = int32(val.(int64))
-        case reflect.Int:
-            val, err = strconv.ParseInt(itp, 10, 64)
-            val = int(val.(int64))
-        case reflect.Int64:
-            val, err = strconv.ParseInt(itp, 10, 64)
-            val = val.(int64)
-        case reflect.Float32:
-            val, err = strconv.ParseFloat(itp, 32)
-            val = float32(val.(float64))
-        case reflect.Float64:
-            val, err = strconv.ParseFloat(itp, 64)
-            val = val.(float64)
-        case reflect.String:
-            val = itp
-        default:
-            panic("Function type not supported")
+    if w.ctx == nil {
+        in[1] = reflect.Zero(t.In(1))
+    }
+    if takesDocString {
+        in[len(in) - 1] = reflect.ValueOf(w.DocString.Content)
+    }
+    for i := 2; i < params; i++ {
+        var pt *parameterType
+        if i - 2 < len(s.params) {
+            pt = s.params[i - 2]
         }
+        val, err := s.types.convertParam(pt, w.regexParams[i - 1], t.In(i))
         if err != nil {
-            panic(err)
+            return nil, err
+        }
+        in[i] = val
+    }
+    return in, nil
+}
+
+var worldType = reflect.TypeOf(&World{})
+
+// Compiles the pattern and checks that f can be called for every step
+// it matches.
+func createstepdef(p string, f interface{}, types *parameterTypes) (stepdef, error) {
+    r, err := re.Compile(p)
+    if err != nil {
+        return stepdef{}, stepdefError(p, f, "invalid pattern: %s", err)
+    }
+    if f != nil {
+        if err := checkStepFunc(r, f, types); err != nil {
+            return stepdef{}, stepdefError(p, f, "%s", err)
+        }
+    }
+    return stepdef{r: r, f: f, types: types, location: funcLocation(f)}, nil
+}
+
+// Compiles a Cucumber Expression and checks that f can be called for
+// every step it matches.
+func createExpressionStepdef(expr string, f interface{}, types *parameterTypes) (stepdef, error) {
+    r, params, err := compileExpression(expr, types)
+    if err != nil {
+        return stepdef{}, stepdefError(expr, f, "%s", err)
+    }
+    if f != nil {
+        if err := checkStepFunc(r, f, types); err != nil {
+            return stepdef{}, stepdefError(expr, f, "%s", err)
+        }
+    }
+    return stepdef{r: r, f: f, expr: expr, params: params, types: types, location: funcLocation(f)}, nil
+}
+
+// The step function must take the World, the context and one parameter
+// of a convertible type per capture group, optionally followed by a
+// string receiving the doc string. It may return an error, or a context
+// of the type it takes and an error.
+func checkStepFunc(r *re.Regexp, f interface{}, types *parameterTypes) error {
+    t := reflect.TypeOf(f)
+    if t.Kind() != reflect.Func {
+        return fmt.Errorf("expected a function but got %s", t)
+    }
+    if t.NumIn() < 2 || t.In(0) != worldType {
+        return fmt.Errorf("function must take (*gherkin.World, context, ...) but is %s", t)
+    }
+    switch {
+    case t.NumOut() == 0:
+    case t.NumOut() == 1 && t.Out(0) == errorType:
+    case t.NumOut() == 2 && t.Out(0) == t.In(1) && t.Out(1) == errorType:
+    default:
+        return fmt.Errorf("function must return nothing, error or (%s, error) but is %s", t.In(1), t)
+    }
+    captures := r.NumSubexp()
+    if t.NumIn() != captures + 2 && !(t.NumIn() == captures + 3 && t.In(t.NumIn() - 1).Kind() == reflect.String) {
+        return fmt.Errorf("pattern has %d capture groups but function %s takes %d arguments after the context",
+            captures, t, t.NumIn() - 2)
+    }
+    for i := 2; i < t.NumIn(); i++ {
+        if !types.canConvert(t.In(i)) {
+            return fmt.Errorf("argument %d of type %s is not supported", i + 1, t.In(i))
         }
-        in[i] = reflect.ValueOf(val)
     }
-    r := reflect.ValueOf(s.f)
-    r.Call(in)
+    return nil
 }
 
-func createstepdef(p string, f interface{}) stepdef {
-    r, _ := re.Compile(p)
-    return stepdef{r, f}
+func stepdefError(pattern string, f interface{}, format string, args ...interface{}) error {
+    return fmt.Errorf("step definition %q (%s): %s", pattern, funcLocation(f), fmt.Sprintf(format, args...))
 }
 
-func (s stepdef) execute(line *step, output io.Writer, ctx interface{}) bool {
+// The file:line where the function f is declared.
+func funcLocation(f interface{}) string {
+    v := reflect.ValueOf(f)
+    if f == nil {
+        return "no function"
+    } else if v.Kind() != reflect.Func {
+        return "not a function"
+    }
+    fn := runtime.FuncForPC(v.Pointer())
+    if fn == nil {
+        return "unknown location"
+    }
+    file, line := fn.FileLine(fn.Entry())
+    return fmt.Sprintf("%s:%d", file, line)
+}
+
+// Calls the step function if the step matches. A returned error fails
+// the step, unless it is ErrPending or ErrSkip; a returned context
+// replaces the context for the rest of the scenario.
+func (s stepdef) execute(line *step, env *execution, tags []string) bool {
     if s.r.MatchString(line.String()) {
         if s.f != nil {
             substrs := s.r.FindStringSubmatch(line.String())
+            for i, pt := range s.params {
+                if pt.transform != nil {
+                    substrs[i + 1] = pt.transform(substrs[i + 1])
+                }
+            }
             w := &World{
                 regexParams:substrs,
                 MultiStep:line.mldata,
-                output: output,
-                ctx: ctx}
-            defer func() { line.hasErrors = w.gotAnError }()
-            s.call(w)
+                DocString:line.docString,
+                output: &line.errors,
+                ctx: env.ctx,
+                tags: tags}
+            in, err := s.arguments(w)
+            if err != nil {
+                line.fail(err)
+                return true
+            }
+            if env.dryRun {
+                line.isSkipped = true
+                return true
+            }
+            defer func() { line.hasErrors = line.hasErrors || w.gotAnError }()
+            out := reflect.ValueOf(s.f).Call(in)
+            if len(out) == 0 {
+                return true
+            }
+            if err, _ := out[len(out) - 1].Interface().(error); err != nil {
+                line.fail(err)
+            } else if len(out) == 2 {
+                env.ctx = out[0].Interface()
+            }
         }
         return true
     }
     return false
 }
 
+// Describes how the step definition matches the text.
+func (s stepdef) match(text string) StepMatch {
+    m := StepMatch{Pattern: s.String(), Expression: s.expr != "", Location: s.location}
+    loc := s.r.FindStringSubmatchIndex(text)
+    for i := 2; i + 1 < len(loc); i += 2 {
+        arg := StepArgument{Start: loc[i]}
+        if loc[i] >= 0 {
+            arg.Value = text[loc[i]:loc[i + 1]]
+        }
+        m.Arguments = append(m.Arguments, arg)
+    }
+    return m
+}
+
 func (s stepdef) String() string {
+    if s.expr != "" {
+        return s.expr
+    }
     return s.r.String()
 }
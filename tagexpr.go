@@ -0,0 +1,148 @@
+package gherkin
+
+import (
+    "fmt"
+    "strings"
+)
+
+// A boolean expression over tags, such as "(@api or @ui) and not @wip".
+type TagExpression interface {
+    // Whether a scenario carrying tags satisfies the expression.
+    Evaluate(tags []string) bool
+    String() string
+}
+
+// Parses a tag expression made of tags, "and", "or", "not" and
+// parentheses. "not" binds tighter than "and", which binds tighter than
+// "or". An empty expression matches every scenario.
+func ParseTagExpression(expr string) (TagExpression, error) {
+    p := &tagExprParser{tokens: tokenizeTagExpression(expr)}
+    if len(p.tokens) == 0 {
+        return tagTrue{}, nil
+    }
+    e, err := p.parseOr()
+    if err != nil {
+        return nil, fmt.Errorf("invalid tag expression %q: %s", expr, err)
+    }
+    if p.pos < len(p.tokens) {
+        return nil, fmt.Errorf("invalid tag expression %q: unexpected %q", expr, p.tokens[p.pos])
+    }
+    return e, nil
+}
+
+func tokenizeTagExpression(expr string) []string {
+    expr = strings.Replace(expr, "(", " ( ", -1)
+    expr = strings.Replace(expr, ")", " ) ", -1)
+    return strings.Fields(expr)
+}
+
+type tagExprParser struct {
+    tokens []string
+    pos int
+}
+
+func (p *tagExprParser) peek() string {
+    if p.pos < len(p.tokens) {
+        return p.tokens[p.pos]
+    }
+    return ""
+}
+
+func (p *tagExprParser) next() string {
+    token := p.peek()
+    p.pos++
+    return token
+}
+
+func (p *tagExprParser) parseOr() (TagExpression, error) {
+    left, err := p.parseAnd()
+    for err == nil && p.peek() == "or" {
+        p.next()
+        var right TagExpression
+        if right, err = p.parseAnd(); err == nil {
+            left = tagOr{left, right}
+        }
+    }
+    return left, err
+}
+
+func (p *tagExprParser) parseAnd() (TagExpression, error) {
+    left, err := p.parseNot()
+    for err == nil && p.peek() == "and" {
+        p.next()
+        var right TagExpression
+        if right, err = p.parseNot(); err == nil {
+            left = tagAnd{left, right}
+        }
+    }
+    return left, err
+}
+
+func (p *tagExprParser) parseNot() (TagExpression, error) {
+    switch token := p.next(); {
+    case token == "not":
+        e, err := p.parseNot()
+        return tagNot{e}, err
+    case token == "(":
+        e, err := p.parseOr()
+        if err == nil && p.next() != ")" {
+            err = fmt.Errorf("missing closing parenthesis")
+        }
+        return e, err
+    case token == "":
+        return nil, fmt.Errorf("unexpected end of expression")
+    case !strings.HasPrefix(token, "@"):
+        return nil, fmt.Errorf("expected a tag but found %q", token)
+    default:
+        return tagLiteral(token), nil
+    }
+}
+
+type tagTrue struct{}
+
+func (e tagTrue) Evaluate(tags []string) bool { return true }
+func (e tagTrue) String() string { return "" }
+
+type tagLiteral string
+
+func (e tagLiteral) Evaluate(tags []string) bool {
+    for _, tag := range tags {
+        if tag == string(e) {
+            return true
+        }
+    }
+    return false
+}
+
+func (e tagLiteral) String() string { return string(e) }
+
+type tagNot struct {
+    expr TagExpression
+}
+
+func (e tagNot) Evaluate(tags []string) bool { return !e.expr.Evaluate(tags) }
+func (e tagNot) String() string { return "not " + e.expr.String() }
+
+type tagAnd struct {
+    left, right TagExpression
+}
+
+func (e tagAnd) Evaluate(tags []string) bool {
+    return e.left.Evaluate(tags) && e.right.Evaluate(tags)
+}
+
+func (e tagAnd) String() string {
+    return "(" + e.left.String() + " and " + e.right.String() + ")"
+}
+
+type tagOr struct {
+    left, right TagExpression
+}
+
+func (e tagOr) Evaluate(tags []string) bool {
+    return e.left.Evaluate(tags) || e.right.Evaluate(tags)
+}
+
+func (e tagOr) String() string {
+    return "(" + e.left.String() + " or " + e.right.String() + ")"
+}
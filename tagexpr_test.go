@@ -0,0 +1,66 @@
+package gherkin
+
+import (
+    "testing"
+    . "github.com/tychofreeman/go-matchers"
+)
+
+func evaluate(t *testing.T, expr string, tags ...string) bool {
+    e, err := ParseTagExpression(expr)
+    AssertThat(t, err, Equals(nil))
+    return e.Evaluate(tags)
+}
+
+func TestEmptyTagExpressionMatchesEverything(t *testing.T) {
+    AssertThat(t, evaluate(t, ""), IsTrue)
+    AssertThat(t, evaluate(t, "  ", "@a"), IsTrue)
+}
+
+func TestTagExpressionMatchesSingleTag(t *testing.T) {
+    AssertThat(t, evaluate(t, "@smoke", "@smoke"), IsTrue)
+    AssertThat(t, evaluate(t, "@smoke", "@slow"), IsFalse)
+}
+
+func TestTagExpressionAndNot(t *testing.T) {
+    AssertThat(t, evaluate(t, "@smoke and not @slow", "@smoke"), IsTrue)
+    AssertThat(t, evaluate(t, "@smoke and not @slow", "@smoke", "@slow"), IsFalse)
+}
+
+func TestTagExpressionParentheses(t *testing.T) {
+    expr := "(@api or @ui) and not @wip"
+    AssertThat(t, evaluate(t, expr, "@ui"), IsTrue)
+    AssertThat(t, evaluate(t, expr, "@api", "@wip"), IsFalse)
+    AssertThat(t, evaluate(t, expr, "@db"), IsFalse)
+}
+
+func TestTagExpressionPrecedence(t *testing.T) {
+    AssertThat(t, evaluate(t, "@a or @b and @c", "@a"), IsTrue)
+    AssertThat(t, evaluate(t, "not @a or @b", "@a", "@b"), IsTrue)
+}
+
+func TestInvalidTagExpressions(t *testing.T) {
+    for _, expr := range []string{"@a and", "(@a or @b", "@a @b", "smoke", "@a)"} {
+        _, err := ParseTagExpression(expr)
+        AssertThat(t, err == nil, IsFalse)
+    }
+}
+
+func TestRunnerOnlyRunsScenariosMatchingTagFilter(t *testing.T) {
+    names := []string{}
+    g := createWriterlessRunner()
+    g.RegisterStepDef("(.*)", func(w *World, ctx *Context, name string) { names = append(names, name) })
+    err := g.SetTagFilter("@smoke and not @slow")
+    AssertThat(t, err, Equals(nil))
+
+    rpt := g.Execute(`@smoke
+    Feature:
+        Scenario:
+            Given fast
+        @slow
+        Scenario:
+            Given slow
+    `, &Context{})
+
+    AssertThat(t, names, Equals([]string{"fast"}))
+    AssertThat(t, rpt.scenarioCount, Equals(1))
+}
@@ -10,9 +10,28 @@ type World struct {
     regexParams []string
     regexParamIndex int
     MultiStep []map[string]string
+    // The doc string argument of the step, or nil if it has none.
+    DocString *DocString
     output io.Writer
     gotAnError bool
     ctx interface{}
+    tags []string
+}
+
+// Returns the tags of the executing scenario, including those inherited
+// from its feature, rule and examples.
+func (w *World) Tags() []string {
+    return w.tags
+}
+
+// Whether the executing scenario carries the given tag, e.g. "@wip".
+func (w *World) HasTag(tag string) bool {
+    for _, t := range w.tags {
+        if t == tag {
+            return true
+        }
+    }
+    return false
 }
 
 // Allows World to be used with the go-matchers AssertThat() function.
@@ -22,7 +41,7 @@ func (w *World) Errorf(format string, args ...interface{}) {
         if len(args) == 0 {
             fmt.Fprintf(w.output, format)
         } else {
-            fmt.Fprintf(w.output, format, args)
+            fmt.Fprintf(w.output, format, args...)
         }
     }
 }